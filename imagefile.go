@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jvanvugt/go-raytracer/raytracer"
+)
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+func writePNG(path string, img image.Image) error {
+	return writeFile(path, func(w io.Writer) error { return png.Encode(w, img) })
+}
+
+// writeFile creates the file at path and writes to it with encode
+func writeFile(path string, encode func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := encode(f); err != nil {
+		f.Close()
+		return err
+	}
+	// Closing flushes the file, which can fail too, for example when the disk is full
+	return f.Close()
+}
+
+// imageFormat is the format for the extension of path: png, ppm or jpeg
+func imageFormat(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".png":
+		return "png", nil
+	case ".ppm":
+		return "ppm", nil
+	case ".jpg", ".jpeg":
+		return "jpeg", nil
+	default:
+		return "", fmt.Errorf("%s: unknown image format %q, expected .png, .ppm, .jpg or .jpeg", path, ext)
+	}
+}
+
+// writeImage writes an image in the format given by the extension of path, JPEGs with the quality set by -quality
+func writeImage(path string, img image.Image) error {
+	format, err := imageFormat(path)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "ppm":
+		nrgba, ok := img.(*image.NRGBA)
+		if !ok {
+			return fmt.Errorf("%s: PPM output only supports 8 bits per channel", path)
+		}
+		return writeFile(path, func(w io.Writer) error { return raytracer.WritePPM(w, nrgba) })
+	case "jpeg":
+		// The rendered images are opaque, so dropping the alpha channel loses nothing
+		return writeFile(path, func(w io.Writer) error { return jpeg.Encode(w, img, &jpeg.Options{Quality: *quality}) })
+	default:
+		return writePNG(path, img)
+	}
+}
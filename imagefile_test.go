@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jvanvugt/go-raytracer/raytracer"
+)
+
+func TestWriteImageReportsErrors(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	// A directory that doesn't exist can't be written to, even by root
+	missing := filepath.Join(t.TempDir(), "missing", "out.png")
+	if err := writeImage(missing, img); err == nil {
+		t.Errorf("writing to %s succeeded", missing)
+	}
+	unknown := filepath.Join(t.TempDir(), "out.gif")
+	if err := writeImage(unknown, img); err == nil || !strings.Contains(err.Error(), unknown) {
+		t.Errorf("writing to %s gave %v, want an error naming the path", unknown, err)
+	}
+}
+
+// fileSize writes img to path and returns the size of the file
+func fileSize(t *testing.T, path string, img image.Image) int64 {
+	t.Helper()
+	if err := writeImage(path, img); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestJPEGQualityChangesTheSize(t *testing.T) {
+	cfg := raytracer.DefaultRenderConfig()
+	cfg.Width, cfg.Height, cfg.Samples = 64, 36, 4
+	camera := raytracer.NewCamera(raytracer.Vec3{}, raytracer.Vec3{Z: 1}, raytracer.Vec3{Y: 1}, raytracer.DefaultFieldOfView, 0, 0, &cfg)
+	img := raytracer.Render(context.Background(), raytracer.NewScene(raytracer.Scenes["dielectrics"](), camera, raytracer.DefaultBackground), cfg)
+
+	defer func(previous int) { *quality = previous }(*quality)
+	dir := t.TempDir()
+	*quality = 50
+	low := fileSize(t, filepath.Join(dir, "low.jpg"), img)
+	*quality = 95
+	high := fileSize(t, filepath.Join(dir, "high.jpeg"), img)
+	if low >= high {
+		t.Errorf("the JPEG is %d bytes at quality 50 and %d at quality 95, want it smaller", low, high)
+	}
+}
+
+func TestPNGKeeps16Bits(t *testing.T) {
+	fb := raytracer.NewFramebuffer(1024, 1)
+	for x := 0; x < fb.Width; x++ {
+		gray := float32(x) / float32(fb.Width-1)
+		fb.Set(x, 0, raytracer.Vec3{X: gray, Y: gray, Z: gray})
+	}
+	path := filepath.Join(t.TempDir(), "gradient.png")
+	if err := writePNG(path, fb.Image16(1)); err != nil {
+		t.Fatal(err)
+	}
+	img, err := readPNG(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[uint32]bool{}
+	for x := 0; x < fb.Width; x++ {
+		r, _, _, _ := img.At(x, 0).RGBA()
+		values[r] = true
+	}
+	if len(values) <= 256 {
+		t.Errorf("the gradient has %d distinct values after a round trip, want more than 256", len(values))
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"image/color"
+	"image/draw"
+	"strings"
+	"unicode/utf8"
+)
+
+const glyphWidth = 5
+const glyphHeight = 7
+
+// glyphs is a 5x7 pixel font of the capital letters, in which # marks the pixels that are drawn.
+// Other characters are drawn as spaces.
+var glyphs = map[rune][glyphHeight]string{
+	'A': {".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'B': {"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####."},
+	'C': {".###.", "#...#", "#....", "#....", "#....", "#...#", ".###."},
+	'D': {"####.", "#...#", "#...#", "#...#", "#...#", "#...#", "####."},
+	'E': {"#####", "#....", "#....", "####.", "#....", "#....", "#####"},
+	'F': {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'G': {".###.", "#...#", "#....", "#.###", "#...#", "#...#", ".####"},
+	'H': {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'I': {".###.", "..#..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'J': {"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##.."},
+	'K': {"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#"},
+	'L': {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'M': {"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#"},
+	'N': {"#...#", "#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#"},
+	'O': {".###.", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'P': {"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	'Q': {".###.", "#...#", "#...#", "#...#", "#.#.#", "#..#.", ".##.#"},
+	'R': {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'S': {".###.", "#...#", "#....", ".###.", "....#", "#...#", ".###."},
+	'T': {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#.."},
+	'U': {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'V': {"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W': {"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "#.#.#", ".#.#."},
+	'X': {"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#"},
+	'Y': {"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#.."},
+	'Z': {"#####", "....#", "...#.", "..#..", ".#...", "#....", "#####"},
+	'-': {".....", ".....", ".....", "#####", ".....", ".....", "....."},
+}
+
+// drawLabel writes text in capitals onto img, centered on x with its top at y, with every pixel of the
+// font scale by scale pixels large. A shadow behind the letters keeps them readable on light backgrounds.
+func drawLabel(img draw.Image, text string, x int, y int, scale int) {
+	text = strings.ToUpper(text)
+	width := (utf8.RuneCountInString(text)*(glyphWidth+1) - 1) * scale
+	drawText(img, text, x-width/2+scale, y+scale, scale, color.Black)
+	drawText(img, text, x-width/2, y, scale, color.White)
+}
+
+// drawText writes text onto img in color c, with its top left corner at (x, y)
+func drawText(img draw.Image, text string, x int, y int, scale int, c color.Color) {
+	for _, char := range text {
+		glyph := glyphs[char]
+		for row, line := range glyph {
+			for column, pixel := range line {
+				if pixel != '#' {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						img.Set(x+column*scale+dx, y+row*scale+dy, c)
+					}
+				}
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
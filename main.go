@@ -1,155 +1,284 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"image"
-	"image/png"
+	"image/draw"
 	"log"
 	"math"
-	"math/rand"
 	"os"
+	"os/signal"
+	"reflect"
+	"runtime"
 	"runtime/pprof"
-	"sync"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/jvanvugt/go-raytracer/raytracer"
 )
 
-const imageWidth = 1280
-const imageHeight = 720
-const fieldOfView float32 = 90.0
-const numSamples = 100
-const maxBounces = 50
+const samplesPerPass = 4
+const edgeThreshold = 0.2
+const autoExposureKey = 0.18
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to `file`")
+var imageWidth = flag.Int("width", 1280, "width of the image in pixels")
+var imageHeight = flag.Int("height", 720, "height of the image in pixels")
+var numSamples = flag.Int("samples", 100, "number of samples per pixel")
+var maxBounces = flag.Int("bounces", 50, "maximum number of bounces of a path")
+var aperture = flag.Float64("aperture", 0, "diameter of the camera lens, for depth of field")
+var focusDist = flag.Float64("focus-dist", 0, "distance at which objects are in focus with -aperture (default the distance to the camera target)")
+var ortho = flag.Float64("ortho", 0, "use an orthographic camera that sees `height` units of the scene vertically, instead of perspective")
+var autoframe = flag.Bool("autoframe", false, "place the camera so that it sees the whole scene, except infinite shapes like planes")
+var motionBlur = flag.Bool("motion-blur", false, "open the shutter from time 0 to 1 instead of only at 0, so that moving objects blur")
+var roulette = flag.Int("roulette", 0, "from bounce `n` on, terminate paths at random with a chance that grows as they carry less light (0 disables)")
+var frames = flag.Int("frames", 0, "render an animation of `n` frames orbiting the scene to frame_<i>.png instead of a single image")
+var tiles = flag.Int("tiles", runtime.NumCPU(), "number of tiles the image is split into to render them in parallel")
+var outPath = flag.String("out", "out.png", "write the image to `file`, as PNG, PPM or JPEG by its extension (.png, .ppm, .jpg or .jpeg)")
+var quality = flag.Int("quality", 90, "`quality` of JPEG output, from 1 to 100")
+var sceneName = flag.String("scene", "dielectrics", "built-in scene to render (dielectrics, metal-balls, custom, sphere-field, far-away, glow, motion, fog or blobs), or a JSON scene `file`")
+var targetRMSE = flag.Float64("target-rmse", 0, "render in passes until the RMSE between successive passes drops below `rmse`, taking at most -samples samples")
+var noiseReadout = flag.Bool("noise-readout", false, "render -samples samples in passes and print the estimated noise level after each pass")
+var skyLighting = flag.Bool("sky-lighting", true, "let the background light the scene instead of only showing behind it")
+var scanline = flag.Int("scanline", -1, "only render image row `y`, printing the color and samples of every pixel and writing it to scanline.png")
+var refineThreshold = flag.Float64("refine-threshold", 0, "start with a quick preview and keep doubling the samples of pixels whose standard error is above `threshold`")
+var refinePasses = flag.Int("refine-passes", 8, "maximum number of passes for -refine-threshold")
+var worldScale = flag.Float64("world-scale", 1, "number of scene units per meter, used to scale the intersection epsilons")
+var edgePreview = flag.Bool("edge-preview", false, "quickly render a single sample per pixel and write its high-contrast edges to edges.png")
+var lutPath = flag.String("lut", "", "color grade the output with the 3D LUT in the .cube `file`")
+var sceneInfo = flag.Bool("scene-info", false, "print the number of primitives, the size of the BVH and an estimate of the memory it takes and exit without rendering")
+var bitDepth = flag.Int("bit-depth", 8, "bits per channel of the output image: 8 or 16")
+var antithetic = flag.Bool("antithetic", false, "pair every sample with one mirrored around the pixel center")
+var despeckle = flag.Float64("despeckle", 0, "replace pixels more than `factor` times as bright as the median of their neighbours by that median")
+var maxDiffuse = flag.Int("max-diffuse", -1, "maximum number of diffuse bounces of a path, if not negative")
+var maxSpecular = flag.Int("max-specular", -1, "maximum number of specular (mirror and glass) bounces of a path, if not negative")
+var foveate = flag.String("foveate", "", "concentrate samples around the pixel `x,y` and take fewer towards the edges")
+var grain = flag.Float64("grain", 0, "add film grain with the given `intensity` to the output")
+var grainShadows = flag.Bool("grain-shadows", false, "make -grain stronger in the shadows than in the highlights")
+var maskPath = flag.String("mask", "", "only render the pixels that are not black in the PNG `file`")
+var basePath = flag.String("base", "", "with -mask, take the pixels outside the mask from the PNG `file`")
+var bracket = flag.String("bracket", "", "also write the image at each of the comma separated exposure `stops` (like -2,0,2) to out_ev<stop>.png")
+var backgroundFlag = flag.String("background", "gradient", "the light from behind the scene: gradient, a color `r,g,b`, or an equirectangular PNG file")
+var bgExposure = flag.Float64("bg-exposure", 0, "change the exposure of the directly visible background by `stops`, without changing how it lights the scene")
+var minSamplesEdge = flag.Int("min-samples-edge", 16, "minimum number of samples for -refine-threshold on pixels at high-contrast edges")
+var minSamplesInterior = flag.Int("min-samples-interior", 2, "minimum number of samples for -refine-threshold on pixels away from edges")
+var autoExposure = flag.Bool("auto-exposure", false, "change the exposure so that the median luminance of the image becomes middle gray")
+var spawnOffset = flag.Float64("spawn-offset", 0, "offset bounced rays from the surface by `scale` times a distance proportional to the hit distance")
+var materialPreview = flag.Bool("material-preview", false, "render a grid of spheres with every material in the library, labeled by name and under studio lights, instead of -scene")
+var indirectClamp = flag.Float64("indirect-clamp", 0, "limit the luminance of indirect light to `max` to remove fireflies, leaving direct light unclamped")
+var clampMax = flag.Float64("clampmax", 0, "limit the luminance of every sample to `max` to remove fireflies, keeping its hue (0 disables)")
+var reflectiveFloorAmount = flag.Float64("reflective-floor", 0, "add a floor that reflects the scene with the given `reflectivity`")
+var floorHeight = flag.Float64("floor-height", -0.5, "height of the -reflective-floor")
+var tonemap = flag.String("tonemap", "none", "tone mapping of the output: none (clip) or reinhard")
+var adaptiveTolerance = flag.Float64("adaptive-tolerance", 0, "stop sampling a pixel once the standard error of its luminance is below `tolerance`, taking at most -samples samples")
+var adaptiveMinSamples = flag.Int("adaptive-min-samples", 16, "minimum number of samples per pixel with -adaptive-tolerance")
+var gamma = flag.Float64("gamma", 2, "gamma of the display the output is corrected for, and of -background images; 1 leaves them linear")
+var seed = flag.Int64("seed", 0, "base `seed` of the random numbers; the same seed gives the same image")
+var mode = flag.String("mode", "color", "what to render: color (path traced), normals (of the first hit) or cost (a heatmap of the intersection tests per pixel)")
+var stratified = flag.Bool("stratified", false, "spread the samples of every pixel over a grid when -samples is a square number")
+var ssaa = flag.Int("ssaa", 1, "supersample by rendering at `n` times the width and height and averaging every n by n block")
+var filter = flag.String("filter", "box", "pixel reconstruction filter: box, tent or gaussian")
+var progress = flag.Bool("progress", false, "print the share of the image that is done and the estimated time remaining to stderr while rendering")
+var printStats = flag.Bool("stats", false, "print the wall time, rays cast and bounces per primary ray after rendering")
+var bounceLayers = flag.Int("bounce-layers", 0, "also write the light gathered at each of the first `n` bounces to bounce_<i>.png")
 
-// Ray from origin in a direction
-type Ray struct {
-	Origin    Vec3
-	Direction Vec3
-}
-
-// At computes the point on the ray at t
-func (ray *Ray) At(t float32) Vec3 {
-	return Add(ray.Origin, MulScalar(t, ray.Direction))
+// newCamera creates the camera set by -ortho, -aperture and -focus-dist at pos looking at target
+func newCamera(pos raytracer.Vec3, target raytracer.Vec3, up raytracer.Vec3, cfg *raytracer.RenderConfig) raytracer.Camera {
+	if *ortho > 0 {
+		return raytracer.NewOrthographicCamera(pos, target, up, float32(*ortho), cfg)
+	}
+	return raytracer.NewCamera(pos, target, up, raytracer.DefaultFieldOfView, float32(*aperture), float32(*focusDist), cfg)
 }
 
-// Hit represents data about a ray hitting an object
-type Hit struct {
-	T        float32
-	Position Vec3
-	Normal   Vec3
-	Material Material
+// renderRefining starts with a single sample for every pixel and then keeps doubling the samples of
+// the pixels that are still noisy, writing the image to -out after every pass, until ctx is cancelled.
+// The light gathered at each bounce is averaged into layers.
+func renderRefining(ctx context.Context, scene *raytracer.Scene, cfg *raytracer.RenderConfig, fb *raytracer.Framebuffer, layers []*raytracer.Framebuffer, threshold float32, passes int, lut *raytracer.LUT) {
+	fb.TrackVariance()
+	counts := make([]int, cfg.Width*cfg.Height)
+	var edges *raytracer.Framebuffer
+	for pass := 0; pass < passes; pass++ {
+		refined := raytracer.Refine(ctx, scene, cfg, fb, layers, counts, edges, threshold)
+		fmt.Printf("pass %d: refined %d pixels\n", pass, refined)
+		if err := writeImage(*outPath, outputImage(processed(fb, cfg), lut)); err != nil {
+			log.Fatalf("could not write image to %s: %v", *outPath, err)
+		}
+		if refined == 0 || ctx.Err() != nil {
+			break
+		}
+		// Thin features can slip between the first samples of a pixel and leave it looking converged,
+		// so pixels on edges get a higher minimum number of samples
+		edges = fb.EdgeMap(edgeThreshold)
+	}
 }
 
-// NewHit creates a Hit object
-func NewHit(t float32, ray Ray, normal Vec3, material Material) *Hit {
-	return &Hit{
-		t,
-		ray.At(t),
-		normal,
-		material,
+// RenderSequence renders an animation of frames frames to frame_0000.png, frame_0001.png and so on.
+// Every frame gets its own camera, placed by calling path with t going from 0 for the first frame
+// to 1 for the last, instead of the camera of the scene. The frames get the same post-processing and
+// color grading by lut as a single image. When ctx is cancelled the frame being rendered is written as
+// far as it got, and the sequence stops.
+func RenderSequence(ctx context.Context, scene *raytracer.Scene, frames int, path func(t float32) (pos raytracer.Vec3, target raytracer.Vec3, up raytracer.Vec3), cfg *raytracer.RenderConfig, lut *raytracer.LUT) {
+	for i := 0; i < frames; i++ {
+		var t float32
+		if frames > 1 {
+			t = float32(i) / float32(frames-1)
+		}
+		pos, target, up := path(t)
+		frame := *scene
+		frame.Camera = newCamera(pos, target, up, cfg)
+		frame.Camera.MotionBlur = *motionBlur
+		fb := raytracer.NewFramebuffer(cfg.Width, cfg.Height)
+		raytracer.RenderPass(ctx, &frame, cfg, fb, nil, cfg.Samples, 0)
+		filename := fmt.Sprintf("frame_%04d.png", i)
+		if err := writePNG(filename, outputImage(processed(fb, cfg), lut)); err != nil {
+			log.Fatal("could not write frame: ", err)
+		}
+		fmt.Println("wrote", filename)
+		if ctx.Err() != nil {
+			return
+		}
 	}
 }
 
-// Camera to shoot rays from
-type Camera struct {
-	Position   Vec3
-	BottomLeft Vec3
-	PixelStepX Vec3
-	PixelStepY Vec3
+// orbitPath circles the camera once around the point 2 units in front of the default camera, starting
+// at the default camera
+func orbitPath(t float32) (pos raytracer.Vec3, target raytracer.Vec3, up raytracer.Vec3) {
+	center := raytracer.Vec3{Z: 2}
+	angle := float64(2 * raytracer.Pi * t)
+	pos = raytracer.Add(center, raytracer.Vec3{X: -2 * float32(math.Sin(angle)), Z: -2 * float32(math.Cos(angle))})
+	return pos, center, raytracer.Vec3{Y: 1}
 }
 
-func setupCamera(cameraPos Vec3, cameraTarget Vec3, up Vec3) Camera {
-	cameraDirection := Normalize(Sub(cameraTarget, cameraPos))
-	horizontalDirection := Cross(Normalize(up), cameraDirection)
-	verticalDirection := Cross(Normalize(cameraDirection), Normalize(horizontalDirection))
-	halfWidth := float32(math.Tan(float64(Deg2Rad(fieldOfView)) / 2.0))
-	halfHeight := halfWidth * float32(imageHeight) / float32(imageWidth)
-	pixelStepX := MulScalar(2*halfWidth/(imageWidth-1), horizontalDirection)
-	pixelStepY := MulScalar(2*halfHeight/(imageHeight-1), verticalDirection)
-	bottomLeft := Sub(Sub(cameraDirection, MulScalar(halfWidth, horizontalDirection)), MulScalar(halfHeight, verticalDirection))
-	return Camera{cameraPos, bottomLeft, pixelStepX, pixelStepY}
+// renderScanline renders row y of the image (counted from the top), prints the color and number of
+// samples of every pixel and writes the row to scanline.png
+func renderScanline(scene *raytracer.Scene, cfg *raytracer.RenderConfig, y int) {
+	fb := raytracer.NewFramebuffer(cfg.Width, 1)
+	for x := 0; x < cfg.Width; x++ {
+		color, samples := raytracer.SamplePixel(scene, cfg, x, y, cfg.Samples)
+		fmt.Println(x, color, samples)
+		fb.Set(x, 0, color)
+	}
+	if err := writePNG("scanline.png", fb.Image(cfg.Gamma)); err != nil {
+		log.Fatal("could not write scanline: ", err)
+	}
 }
 
-func (camera *Camera) getRay(x float32, y float32) Ray {
-	direction := Add(Add(camera.BottomLeft, MulScalar(x, camera.PixelStepX)), MulScalar(y, camera.PixelStepY))
-	return Ray{camera.Position, Normalize(direction)}
+// downsampled averages the framebuffer down to the output size if it is supersampled with -ssaa
+func downsampled(fb *raytracer.Framebuffer) *raytracer.Framebuffer {
+	if *ssaa == 1 {
+		return fb
+	}
+	return fb.Downsample(*ssaa)
 }
 
-// My own scene
-// var world = []Shape{
-// 	Sphere{Vec3{1, 1, 3}, 0.5, Metal{Vec3{1, 1, 1}, 0.3}},
-// 	Plane{Vec3{0, 1, 0}, -1, Lambertian{Vec3{0.7, 0.8, 1.0}}},
-// 	Sphere{Vec3{0, -0.5, 2}, 0.5, Lambertian{Vec3{0, 1, 0}}},
-// 	Sphere{Vec3{-3, 2, 2}, 0.5, Lambertian{Vec3{1, 1, 0}}},
-// 	Sphere{Vec3{0, 1, 2}, 0.5, Lambertian{Vec3{1, 0, 1}}},
-// }
-
-// Two metal balls
-// var world = []Shape{
-// 	Plane{Vec3{0, 1, 0}, -1, Lambertian{Vec3{140 / 255., 245 / 255., 98 / 255.}}},
-// 	Sphere{Vec3{-2, 0, 2}, 1, Metal{Vec3{1, 1, 1}, 0.2}},
-// 	Sphere{Vec3{0, 0, 2}, 1, Lambertian{Vec3{255 / 255., 200 / 255., 210 / 255.}}},
-// 	Sphere{Vec3{2, 0, 2}, 1, Metal{Vec3{0.8, 0.75, 1}, 0}},
-// }
-
-// Dielectrics
-var world = []Shape{
-	Sphere{Vec3{0, 0, 2}, 0.5, Lambertian{Vec3{0.1, 0.2, 0.5}}},
-	Sphere{Vec3{0, -100.5, 1}, 100, Lambertian{Vec3{0.8, 0.8, 0.0}}},
-	Sphere{Vec3{1, 0, 2}, 0.5, Metal{Vec3{0.8, 0.6, 0.2}, 0}},
-	Sphere{Vec3{-1, 0, 2}, 0.45, Dielectric{1.5}},
+// processed downsamples the framebuffer and applies -mode cost, -despeckle, -auto-exposure and
+// -grain to it. The framebuffer itself is left unchanged, so rendering can go on.
+func processed(fb *raytracer.Framebuffer, cfg *raytracer.RenderConfig) *raytracer.Framebuffer {
+	fb = downsampled(fb).Copy()
+	if cfg.Mode == raytracer.CostMode {
+		fb = fb.Heatmap()
+	}
+	if *despeckle > 0 {
+		fmt.Printf("despeckled %d pixels\n", fb.Despeckle(float32(*despeckle)))
+	}
+	if *autoExposure {
+		ev := fb.AutoExposure(autoExposureKey)
+		fmt.Fprintf(os.Stderr, "auto exposure: %+.2f EV\n", ev)
+		fb = fb.Exposed(ev)
+	}
+	if *grain > 0 {
+		fb.AddGrain(float32(*grain), *grainShadows)
+	}
+	return fb
 }
 
-func castRay(ray Ray, rng *rand.Rand, bounced int) Vec3 {
-	if bounced > maxBounces {
-		return Vec3{0, 0, 0}
+// outputImage converts the framebuffer to an image with the tone mapping set by -tonemap and the
+// bit depth set by -bit-depth, color graded by lut if it isn't nil
+func outputImage(fb *raytracer.Framebuffer, lut *raytracer.LUT) draw.Image {
+	if *tonemap == "reinhard" {
+		fb = fb.ToneMapped()
 	}
-	closest := float32(math.MaxFloat32)
-	var closestHit *Hit
-	for _, shape := range world {
-		hit := shape.Intersect(ray)
-		if hit != nil && hit.T < closest {
-			closest = hit.T
-			closestHit = hit
-		}
+	var img draw.Image
+	if *bitDepth == 16 {
+		img = fb.Image16(float32(*gamma))
+	} else {
+		img = fb.Image(float32(*gamma))
 	}
-
-	if closestHit != nil {
-		didScatter, attenuation, scatteredRay := closestHit.Material.Scatter(ray, *closestHit, rng)
-		if didScatter {
-			return Mul(attenuation, castRay(scatteredRay, rng, bounced+1))
-		}
-		return Vec3{0, 0, 0}
+	if lut != nil {
+		lut.ApplyToImage(img)
 	}
-
-	return Add(MulScalar((ray.Direction.Y+1)/2, Vec3{0.6, 0.6, 1}), MulScalar(1-(ray.Direction.Y+1)/2, Vec3{1, 1, 1}))
+	return img
 }
 
-func getColor(camera *Camera, x int, y int, rng *rand.Rand) Vec3 {
-	color := Vec3{0, 0, 0}
-	for i := 0; i < numSamples; i++ {
-		ray := camera.getRay(float32(x)+rng.Float32()-0.5, float32(y)+rng.Float32()-0.5)
-		color = Add(color, castRay(ray, rng, 0))
+// labelScale is the size of the pixels of the font of the labels for an image of the given height
+func labelScale(height int) int {
+	if height < 240 {
+		return 1
+	}
+	return height / 240
+}
 
+// loadFullSizePNG reads a PNG that must have the same size as the rendered image
+func loadFullSizePNG(cfg *raytracer.RenderConfig, path string) image.Image {
+	img, err := readPNG(path)
+	if err != nil {
+		log.Fatal("could not read image: ", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, cfg.Width, cfg.Height) {
+		log.Fatalf("%s is %v, expected %dx%d", path, img.Bounds().Size(), cfg.Width, cfg.Height)
 	}
-	return DivScalar(float32(numSamples), color)
+	return img
 }
 
-func processTile(img *image.NRGBA, camera *Camera, fromX int, fromY int, toX int, toY int, waitGroup *sync.WaitGroup) {
-	defer waitGroup.Done()
+// printRenderStats prints a summary of the rays counted in stats since start
+func printRenderStats(stats *raytracer.RenderStats, start time.Time) {
+	elapsed := time.Since(start)
+	fmt.Printf("%v, %d rays, %.0f rays/s\n", elapsed.Round(time.Millisecond), stats.Rays, float64(stats.Rays)/elapsed.Seconds())
+	fmt.Printf("%.2f bounces per primary ray, at most %d\n", stats.AverageBounces(), stats.MaxDepth)
+}
 
-	rng := rand.New(rand.NewSource(0))
-	for y := fromY; y < toY; y++ {
-		for x := fromX; x < toX; x++ {
-			color := getColor(camera, x, y, rng)
-			gammaCorrectedColor := Vec3{Sqrt(color.X), Sqrt(color.Y), Sqrt(color.Z)}
-			img.Set(x, imageHeight-y-1, gammaCorrectedColor.RGBA())
+// printSceneInfo prints how many primitives of each type a scene has, the size of its bounding volume
+// hierarchy and an estimate of the memory taken by the shapes and the nodes. Materials, textures and
+// anything else the shapes point to are not counted.
+func printSceneInfo(scene *raytracer.Scene) {
+	counts := map[string]int{}
+	var types []string
+	var size uintptr
+	for _, shape := range scene.Shapes {
+		size += reflect.TypeOf(shape).Size()
+		name := strings.TrimPrefix(fmt.Sprintf("%T", shape), "raytracer.")
+		if counts[name] == 0 {
+			types = append(types, name)
 		}
+		counts[name]++
+	}
+	fmt.Printf("%d primitives\n", len(scene.Shapes))
+	for _, name := range types {
+		fmt.Printf("  %s: %d\n", name, counts[name])
 	}
+	nodes, depth := scene.BVHSize()
+	fmt.Printf("%d BVH nodes, %d deep\n", nodes, depth)
+	size += uintptr(nodes) * unsafe.Sizeof(raytracer.BVHNode{})
+	fmt.Printf("about %.1f KiB for the shapes and the BVH\n", float64(size)/(1<<10))
 }
 
 func main() {
 	flag.Parse()
+	// The first interrupt stops rendering and writes what has been rendered so far, a second one exits
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		signal.Stop(interrupts)
+		fmt.Fprintln(os.Stderr, "interrupted, writing the image rendered so far")
+		cancel()
+	}()
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
 		if err != nil {
@@ -162,22 +291,239 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
-	cameraPos := Vec3{0, 0, 0}
-	target := Vec3{0, 0, 1}
-	up := Vec3{0, 1, 0}
-	camera := setupCamera(cameraPos, target, up)
-
-	img := image.NewNRGBA(image.Rect(0, 0, imageWidth, imageHeight))
-	var waitGroup sync.WaitGroup
-	waitGroup.Add(4)
-	go processTile(img, &camera, 0, 0, imageWidth/2, imageHeight/2, &waitGroup)
-	go processTile(img, &camera, imageWidth/2, 0, imageWidth, imageHeight/2, &waitGroup)
-	go processTile(img, &camera, 0, imageHeight/2, imageWidth/2, imageHeight, &waitGroup)
-	go processTile(img, &camera, imageWidth/2, imageHeight/2, imageWidth, imageHeight, &waitGroup)
-	waitGroup.Wait()
-	fmt.Println("Hello world")
-
-	f, _ := os.Create("out.png")
-	defer f.Close()
-	png.Encode(f, img)
+	if *imageWidth < 2 || *imageHeight < 2 {
+		log.Fatalf("image size must be at least 2x2, got %dx%d", *imageWidth, *imageHeight)
+	}
+	if *tiles < 1 {
+		log.Fatal("need at least one tile, got ", *tiles)
+	}
+	format, err := imageFormat(*outPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *bitDepth != 8 && *bitDepth != 16 {
+		log.Fatal("unsupported bit depth: ", *bitDepth)
+	}
+	if *bitDepth == 16 && format != "png" {
+		log.Fatalf("%s: only PNG output supports 16 bits per channel", *outPath)
+	}
+	if *ssaa < 1 {
+		log.Fatal("-ssaa must be at least 1, got ", *ssaa)
+	}
+	if *ssaa > 1 && (*maskPath != "" || *scanline >= 0) {
+		log.Fatal("-ssaa can't be combined with -mask or -scanline")
+	}
+	if *quality < 1 || *quality > 100 {
+		log.Fatal("JPEG quality must be from 1 to 100, got ", *quality)
+	}
+	config := raytracer.DefaultRenderConfig()
+	cfg := &config
+	cfg.Width, cfg.Height, cfg.Samples, cfg.Bounces, cfg.Tiles = *imageWidth, *imageHeight, *numSamples, *maxBounces, *tiles
+	// With -ssaa the whole render is larger, and only the output is averaged down to the requested size
+	cfg.Width *= *ssaa
+	cfg.Height *= *ssaa
+	cfg.MaxDiffuse, cfg.MaxSpecular = *maxDiffuse, *maxSpecular
+	cfg.SkyLighting = *skyLighting
+	cfg.Antithetic = *antithetic
+	cfg.SpawnOffset = float32(*spawnOffset)
+	cfg.IndirectClamp = float32(*indirectClamp)
+	cfg.SampleClamp = float32(*clampMax)
+	cfg.Roulette = *roulette
+	cfg.Seed = *seed
+	cfg.Stratified = *stratified
+	cfg.MinSamplesEdge, cfg.MinSamplesInterior = *minSamplesEdge, *minSamplesInterior
+	cfg.AdaptiveTolerance, cfg.AdaptiveMinSamples = float32(*adaptiveTolerance), *adaptiveMinSamples
+	var ok bool
+	if cfg.Filter, ok = raytracer.Filters[*filter]; !ok {
+		log.Fatalf("unknown filter %q", *filter)
+	}
+	if cfg.Mode, ok = raytracer.Modes[*mode]; !ok {
+		log.Fatalf("unknown mode %q", *mode)
+	}
+	if *progress {
+		cfg.Progress = os.Stderr
+	}
+
+	camera := newCamera(raytracer.Vec3{}, raytracer.Vec3{Z: 1}, raytracer.Vec3{Y: 1}, cfg)
+	if *sceneName == "" {
+		*sceneName = "dielectrics"
+	}
+	var shapes []raytracer.Shape
+	var labels []raytracer.Label
+	if newScene, ok := raytracer.Scenes[*sceneName]; ok {
+		shapes = newScene()
+	} else {
+		var err error
+		if shapes, camera, err = raytracer.LoadScene(*sceneName, cfg); err != nil {
+			log.Fatal("could not load scene: ", err)
+		}
+	}
+	if *materialPreview {
+		shapes, labels = raytracer.MaterialPreviewScene()
+	}
+	if *autoframe {
+		camera = raytracer.AutoFrameCamera(shapes, raytracer.Vec3{Y: 1}, raytracer.DefaultFieldOfView, cfg)
+	}
+	camera.MotionBlur = *motionBlur
+	if *reflectiveFloorAmount > 0 {
+		shapes = append(shapes, raytracer.NewReflectiveFloor(float32(*floorHeight), float32(*reflectiveFloorAmount)))
+	}
+	if *foveate != "" {
+		if _, err := fmt.Sscanf(*foveate, "%f,%f", &cfg.Fovea.X, &cfg.Fovea.Y); err != nil {
+			log.Fatal("invalid -foveate point: ", err)
+		}
+		cfg.Fovea = raytracer.MulScalar(float32(*ssaa), cfg.Fovea)
+		cfg.Foveated = true
+	}
+	if *tonemap != "none" && *tonemap != "reinhard" {
+		log.Fatalf("unknown tone mapping %q", *tonemap)
+	}
+	var brackets []float32
+	if *bracket != "" {
+		for _, field := range strings.Split(*bracket, ",") {
+			stops, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				log.Fatal("invalid exposure bracket: ", err)
+			}
+			brackets = append(brackets, float32(stops))
+		}
+	}
+	var lut *raytracer.LUT
+	if *lutPath != "" {
+		var err error
+		if lut, err = raytracer.LoadCubeLUT(*lutPath); err != nil {
+			log.Fatal("could not load LUT: ", err)
+		}
+	}
+	cfg.WorldScale = float32(*worldScale)
+	if *gamma <= 0 {
+		log.Fatal("gamma must be positive, got ", *gamma)
+	}
+	cfg.Gamma = float32(*gamma)
+	background := raytracer.DefaultBackground
+	if *materialPreview {
+		background = raytracer.StudioBackground
+	}
+	if *backgroundFlag != "gradient" {
+		var c raytracer.Vec3
+		if _, err := fmt.Sscanf(*backgroundFlag, "%f,%f,%f", &c.X, &c.Y, &c.Z); err == nil {
+			background = raytracer.SolidBackground{Color: c}
+		} else {
+			environment, err := readPNG(*backgroundFlag)
+			if err != nil {
+				log.Fatal("could not read background: ", err)
+			}
+			background = raytracer.EnvironmentMap{Image: environment, Gamma: cfg.Gamma}
+		}
+	}
+	scene := raytracer.NewScene(shapes, camera, background)
+	if *sceneInfo {
+		printSceneInfo(scene)
+		return
+	}
+	cfg.BackgroundScale = float32(math.Pow(2, *bgExposure))
+	var base image.Image
+	if *maskPath != "" {
+		cfg.Mask = loadFullSizePNG(cfg, *maskPath)
+		if *basePath != "" {
+			base = loadFullSizePNG(cfg, *basePath)
+		}
+	}
+
+	if *printStats {
+		cfg.Stats = &raytracer.RenderStats{}
+		defer printRenderStats(cfg.Stats, time.Now())
+	}
+	if *scanline >= 0 {
+		if *scanline >= cfg.Height {
+			log.Fatal("scanline out of range: ", *scanline)
+		}
+		renderScanline(scene, cfg, *scanline)
+		return
+	}
+	if *frames > 0 {
+		RenderSequence(ctx, scene, *frames, orbitPath, cfg, lut)
+		return
+	}
+
+	fb := raytracer.NewFramebuffer(cfg.Width, cfg.Height)
+	if *edgePreview {
+		raytracer.RenderPass(ctx, scene, cfg, fb, nil, 1, 0)
+		if err := writePNG("edges.png", fb.EdgeMap(edgeThreshold).Image(cfg.Gamma)); err != nil {
+			log.Fatal("could not write edge preview: ", err)
+		}
+		return
+	}
+
+	layers := make([]*raytracer.Framebuffer, *bounceLayers)
+	for i := range layers {
+		layers[i] = raytracer.NewFramebuffer(cfg.Width, cfg.Height)
+	}
+	if *refineThreshold > 0 {
+		renderRefining(ctx, scene, cfg, fb, layers, float32(*refineThreshold), *refinePasses, lut)
+	} else if *targetRMSE > 0 || *noiseReadout {
+		if *noiseReadout {
+			fb.TrackVariance()
+		}
+		// Every pass takes samplesPerPass samples, so -samples limits the number of passes
+		passes := cfg.Samples / samplesPerPass
+		if passes < 1 {
+			passes = 1
+		}
+		for pass := 0; pass < passes; pass++ {
+			previous := fb.Copy()
+			raytracer.RenderPass(ctx, scene, cfg, fb, layers, samplesPerPass, pass)
+			if ctx.Err() != nil {
+				break
+			}
+			if pass == 0 {
+				continue
+			}
+			if *noiseReadout {
+				fmt.Printf("pass %d: noise %f\n", pass, fb.StandardError(pass+1))
+			}
+			if *targetRMSE <= 0 {
+				continue
+			}
+			rmse := raytracer.RMSE(previous, fb)
+			fmt.Printf("pass %d: rmse %f\n", pass, rmse)
+			if float64(rmse) < *targetRMSE {
+				break
+			}
+		}
+	} else {
+		raytracer.RenderPass(ctx, scene, cfg, fb, layers, cfg.Samples, 0)
+	}
+	for i, layer := range layers {
+		path := fmt.Sprintf("bounce_%d.png", i)
+		if err := writePNG(path, downsampled(layer).Image(cfg.Gamma)); err != nil {
+			log.Fatal("could not write bounce layer: ", err)
+		}
+	}
+	fb = processed(fb, cfg)
+	for _, stops := range brackets {
+		path := fmt.Sprintf("out_ev%+g.png", stops)
+		if err := writePNG(path, outputImage(fb.Exposed(stops), nil)); err != nil {
+			log.Fatal("could not write exposure bracket: ", err)
+		}
+	}
+	img := outputImage(fb, lut)
+	for _, label := range labels {
+		if x, y, ok := scene.Camera.Project(label.Position, cfg); ok {
+			drawLabel(img, label.Text, int(x) / *ssaa, int(y) / *ssaa, labelScale(*imageHeight))
+		}
+	}
+	if base != nil {
+		for y := 0; y < cfg.Height; y++ {
+			for x := 0; x < cfg.Width; x++ {
+				if !cfg.InMask(x, y) {
+					img.Set(x, y, base.At(x, y))
+				}
+			}
+		}
+	}
+
+	if err := writeImage(*outPath, img); err != nil {
+		log.Fatalf("could not write image to %s: %v", *outPath, err)
+	}
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jvanvugt/go-raytracer/raytracer"
+)
+
+func TestSequenceFramesArePostProcessed(t *testing.T) {
+	defer func(previousGrain float64, previousExposure bool) {
+		*grain, *autoExposure = previousGrain, previousExposure
+	}(*grain, *autoExposure)
+	*grain, *autoExposure = 0.2, true
+	// Inverts every color, so a frame that skips the grading can't match
+	lut := &raytracer.LUT{Size: 2, DomainMax: raytracer.Vec3{X: 1, Y: 1, Z: 1}}
+	for i := 0; i < 8; i++ {
+		lut.Table = append(lut.Table, raytracer.Vec3{X: float32(1 - i&1), Y: float32(1 - i>>1&1), Z: float32(1 - i>>2&1)})
+	}
+	cfg := raytracer.DefaultRenderConfig()
+	cfg.Width, cfg.Height, cfg.Samples = 32, 18, 4
+	scene := raytracer.NewScene(raytracer.Scenes["dielectrics"](), raytracer.Camera{}, raytracer.DefaultBackground)
+	t.Chdir(t.TempDir())
+
+	RenderSequence(context.Background(), scene, 1, orbitPath, &cfg, lut)
+	got, err := os.ReadFile("frame_0000.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, target, up := orbitPath(0)
+	frame := *scene
+	frame.Camera = newCamera(pos, target, up, &cfg)
+	fb := raytracer.NewFramebuffer(cfg.Width, cfg.Height)
+	raytracer.RenderPass(context.Background(), &frame, &cfg, fb, nil, cfg.Samples, 0)
+	if err := writePNG("want.png", outputImage(processed(fb, &cfg), lut)); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("want.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("frame 0 of the sequence differs from the post-processed render")
+	}
+}
@@ -0,0 +1,70 @@
+package raytracer
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Background gives the light coming from a direction in which the ray doesn't hit anything
+type Background interface {
+	Sample(direction Vec3) Vec3
+}
+
+// DefaultBackground is the sky of the built-in scenes, white at the horizon and blue above
+var DefaultBackground Background = GradientBackground{Vec3{1, 1, 1}, Vec3{0.6, 0.6, 1}}
+
+// SolidBackground is the same color in every direction
+type SolidBackground struct {
+	Color Vec3
+}
+
+// Sample the background
+func (bg SolidBackground) Sample(direction Vec3) Vec3 {
+	return bg.Color
+}
+
+// GradientBackground blends from Bottom straight down to Top straight up
+type GradientBackground struct {
+	Bottom Vec3
+	Top    Vec3
+}
+
+// Sample the background
+func (bg GradientBackground) Sample(direction Vec3) Vec3 {
+	return Lerp(bg.Bottom, bg.Top, (direction.Y+1)/2)
+}
+
+// EnvironmentMap is a panorama around the scene in an equirectangular image: the horizontal axis is
+// the angle around the Y axis, starting behind the default camera, and the vertical axis goes from
+// straight up at the top to straight down at the bottom
+type EnvironmentMap struct {
+	Image image.Image
+	// Gamma the image is encoded with, usually 2 like the output
+	Gamma float32
+}
+
+// Sample the color of the pixel in the direction, converted back to linear colors
+func (bg EnvironmentMap) Sample(direction Vec3) Vec3 {
+	u := 0.5 + math.Atan2(float64(direction.X), float64(direction.Z))/(2*math.Pi)
+	v := 0.5 - math.Asin(math.Max(-1, math.Min(1, float64(direction.Y))))/math.Pi
+	return imageColor(bg.Image, u, v, bg.Gamma)
+}
+
+// imageColor is the linear color of the pixel of img at (u, v), from (0, 0) at the top left to (1, 1)
+// at the bottom right, undoing the gamma correction of its channels
+func imageColor(img image.Image, u float64, v float64, gamma float32) Vec3 {
+	bounds := img.Bounds()
+	x := bounds.Min.X + int(u*float64(bounds.Dx()))
+	y := bounds.Min.Y + int(v*float64(bounds.Dy()))
+	if x >= bounds.Max.X {
+		x = bounds.Max.X - 1
+	}
+	if y >= bounds.Max.Y {
+		y = bounds.Max.Y - 1
+	}
+	pixel := color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+	corrected := Vec3{float32(pixel.R) / 65535, float32(pixel.G) / 65535, float32(pixel.B) / 65535}
+	// Correcting for the inverse gamma raises the channels to the power gamma, which decodes them
+	return GammaCorrect(corrected, 1/gamma)
+}
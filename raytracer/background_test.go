@@ -0,0 +1,15 @@
+package raytracer
+
+import (
+	"image"
+	"testing"
+)
+
+func TestImageColorUndoesGammaCorrection(t *testing.T) {
+	img := image.NewNRGBA64(image.Rect(0, 0, 1, 1))
+	linear := Vec3{0.04, 0.25, 0.81}
+	img.Set(0, 0, GammaCorrect(linear, 2.2).RGBA64())
+	if got := imageColor(img, 0, 0, 2.2); !vecCloseTo(got, linear, 1e-3) {
+		t.Errorf("decoding with gamma 2.2 gave %v, want %v", got, linear)
+	}
+}
@@ -0,0 +1,198 @@
+package raytracer
+
+import (
+	"math"
+	"sort"
+)
+
+// unboundedMin and unboundedMax are the corners of the bounding box of shapes that extend infinitely, like planes
+var unboundedMin = Vec3{-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+var unboundedMax = Vec3{math.MaxFloat32, math.MaxFloat32, math.MaxFloat32}
+
+func isUnbounded(min Vec3, max Vec3) bool {
+	return min.X == -math.MaxFloat32 || min.Y == -math.MaxFloat32 || min.Z == -math.MaxFloat32 ||
+		max.X == math.MaxFloat32 || max.Y == math.MaxFloat32 || max.Z == math.MaxFloat32
+}
+
+// boxThickness pads the boxes of the BVH, in meters, so that rays hit the boxes of shapes that are
+// flat along an axis, like quads, despite rounding errors
+const boxThickness = 1e-4
+
+// hitBox checks whether the ray passes through the axis-aligned box from min to max (the slab method),
+// padded by boxThickness in a scene with scale units per meter
+func hitBox(min Vec3, max Vec3, ray Ray, scale float32) bool {
+	padding := Vec3{boxThickness * scale, boxThickness * scale, boxThickness * scale}
+	min, max = Sub(min, padding), Add(max, padding)
+	tMin := float32(0)
+	tMax := float32(math.MaxFloat32)
+	for axis := 0; axis < 3; axis++ {
+		invDirection := 1 / ray.Direction.Component(axis)
+		t0 := (min.Component(axis) - ray.Origin.Component(axis)) * invDirection
+		t1 := (max.Component(axis) - ray.Origin.Component(axis)) * invDirection
+		if invDirection < 0 {
+			t0, t1 = t1, t0
+		}
+		tMin = Max(tMin, t0)
+		tMax = Min(tMax, t1)
+		if tMax < tMin {
+			return false
+		}
+	}
+	return true
+}
+
+// BVHNode is a node of a bounding volume hierarchy: a binary tree of shapes in which every node
+// stores the box around all shapes below it, so that rays missing the box can skip them all
+type BVHNode struct {
+	Min   Vec3
+	Max   Vec3
+	Left  Shape
+	Right Shape
+}
+
+// NewBVH builds a bounding volume hierarchy of shapes. Unbounded shapes would make the box of every
+// node they end up in infinite, so they are kept out of the tree and only added at the top.
+func NewBVH(shapes []Shape) Shape {
+	var bounded, unbounded []Shape
+	for _, shape := range shapes {
+		if isUnbounded(shape.BoundingBox()) {
+			unbounded = append(unbounded, shape)
+		} else {
+			bounded = append(bounded, shape)
+		}
+	}
+	var root Shape
+	if len(bounded) > 0 {
+		root = buildBVH(bounded)
+	}
+	for _, shape := range unbounded {
+		if root == nil {
+			root = shape
+		} else {
+			root = &BVHNode{unboundedMin, unboundedMax, root, shape}
+		}
+	}
+	if root == nil {
+		return &BVHNode{}
+	}
+	return root
+}
+
+// buildBVH splits shapes in two halves along the axis in which their centers are spread out the most
+func buildBVH(shapes []Shape) Shape {
+	if len(shapes) == 1 {
+		return shapes[0]
+	}
+	type centeredShape struct {
+		Shape  Shape
+		Center Vec3
+	}
+	centered := make([]centeredShape, len(shapes))
+	min, max := shapes[0].BoundingBox()
+	lowest, highest := unboundedMax, unboundedMin
+	for i, shape := range shapes {
+		shapeMin, shapeMax := shape.BoundingBox()
+		min, max = minVec3(min, shapeMin), maxVec3(max, shapeMax)
+		center := MulScalar(0.5, Add(shapeMin, shapeMax))
+		lowest, highest = minVec3(lowest, center), maxVec3(highest, center)
+		centered[i] = centeredShape{shape, center}
+	}
+	spread := Sub(highest, lowest)
+	axis := 0
+	if spread.Y > spread.Component(axis) {
+		axis = 1
+	}
+	if spread.Z > spread.Component(axis) {
+		axis = 2
+	}
+
+	sort.SliceStable(centered, func(i, j int) bool {
+		return centered[i].Center.Component(axis) < centered[j].Center.Component(axis)
+	})
+	sorted := make([]Shape, len(centered))
+	for i, c := range centered {
+		sorted[i] = c.Shape
+	}
+	half := len(sorted) / 2
+	return &BVHNode{min, max, buildBVH(sorted[:half]), buildBVH(sorted[half:])}
+}
+
+// WorldBounds is the box around all shapes that are not infinitely large, like planes. Without any
+// such shapes min is larger than max.
+func WorldBounds(shapes []Shape) (min Vec3, max Vec3) {
+	min, max = unboundedMax, unboundedMin
+	for _, shape := range shapes {
+		shapeMin, shapeMax := shape.BoundingBox()
+		if isUnbounded(shapeMin, shapeMax) {
+			continue
+		}
+		min, max = minVec3(min, shapeMin), maxVec3(max, shapeMax)
+	}
+	return min, max
+}
+
+func minVec3(a Vec3, b Vec3) Vec3 {
+	return Vec3{Min(a.X, b.X), Min(a.Y, b.Y), Min(a.Z, b.Z)}
+}
+
+func maxVec3(a Vec3, b Vec3) Vec3 {
+	return Vec3{Max(a.X, b.X), Max(a.Y, b.Y), Max(a.Z, b.Z)}
+}
+
+// Intersect returns the closest hit of the shapes in both children, if the ray passes through the box
+func (node *BVHNode) Intersect(ray Ray, scale float32) (Hit, bool) {
+	if node.Left == nil || !hitBox(node.Min, node.Max, ray, scale) {
+		return Hit{}, false
+	}
+	left, hitLeft := node.Left.Intersect(ray, scale)
+	right, hitRight := node.Right.Intersect(ray, scale)
+	if !hitLeft || (hitRight && right.T < left.T) {
+		return right, hitRight
+	}
+	return left, true
+}
+
+// intersectCounting intersects shape like its Intersect, and adds the number of boxes and shapes the
+// ray was tested against to cost
+func intersectCounting(shape Shape, ray Ray, scale float32, cost *int) (Hit, bool) {
+	*cost++
+	node, ok := shape.(*BVHNode)
+	if !ok {
+		return shape.Intersect(ray, scale)
+	}
+	if node.Left == nil || !hitBox(node.Min, node.Max, ray, scale) {
+		return Hit{}, false
+	}
+	left, hitLeft := intersectCounting(node.Left, ray, scale, cost)
+	right, hitRight := intersectCounting(node.Right, ray, scale, cost)
+	if !hitLeft || (hitRight && right.T < left.T) {
+		return right, hitRight
+	}
+	return left, true
+}
+
+// bvhSize counts the nodes of the bounding volume hierarchy below root and its depth, the largest
+// number of nodes on the way from root to a shape
+func bvhSize(root Shape) (nodes int, depth int) {
+	node, ok := root.(*BVHNode)
+	if !ok || node == nil {
+		return 0, 0
+	}
+	nodes, depth = 1, 1
+	for _, child := range []Shape{node.Left, node.Right} {
+		if child == nil {
+			continue
+		}
+		childNodes, childDepth := bvhSize(child)
+		nodes += childNodes
+		if childDepth+1 > depth {
+			depth = childDepth + 1
+		}
+	}
+	return nodes, depth
+}
+
+// BoundingBox of all shapes in the node
+func (node *BVHNode) BoundingBox() (min Vec3, max Vec3) {
+	return node.Min, node.Max
+}
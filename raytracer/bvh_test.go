@@ -0,0 +1,99 @@
+package raytracer
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// intersectLinear finds the closest hit by testing every shape, as rendering did before the BVH
+func intersectLinear(shapes []Shape, ray Ray) (Hit, bool) {
+	var closest Hit
+	found := false
+	for _, shape := range shapes {
+		if hit, ok := shape.Intersect(ray, 1); ok && (!found || hit.T < closest.T) {
+			closest, found = hit, true
+		}
+	}
+	return closest, found
+}
+
+// randomSpheres scatters n small spheres in a 20 unit cube in front of the origin
+func randomSpheres(n int) []Shape {
+	rng := rand.New(rand.NewSource(1))
+	shapes := make([]Shape, n)
+	for i := range shapes {
+		center := Vec3{20*rng.Float32() - 10, 20*rng.Float32() - 10, 20*rng.Float32() + 5}
+		shapes[i] = Sphere{center, 0.3, Lambertian{SolidColor{Vec3{0.5, 0.5, 0.5}}}}
+	}
+	return shapes
+}
+
+// randomRays shoots n rays from the origin into the half space of the spheres
+func randomRays(n int) []Ray {
+	rng := rand.New(rand.NewSource(2))
+	rays := make([]Ray, n)
+	for i := range rays {
+		rays[i] = Ray{Vec3{0, 0, 0}, Normalize(Vec3{RandomUniform(rng), RandomUniform(rng), 1}), 0}
+	}
+	return rays
+}
+
+func TestBVHFindsTheClosestHit(t *testing.T) {
+	shapes := randomSpheres(500)
+	bvh := NewBVH(shapes)
+	hits := 0
+	for _, ray := range randomRays(1000) {
+		want, wantOk := intersectLinear(shapes, ray)
+		got, ok := bvh.Intersect(ray, 1)
+		if ok != wantOk || got.T != want.T {
+			t.Fatalf("BVH hit %v at %v, the linear scan %v at %v", ok, got.T, wantOk, want.T)
+		}
+		if ok {
+			hits++
+		}
+	}
+	if hits == 0 {
+		t.Error("none of the rays hit a sphere")
+	}
+}
+
+func TestBVHSize(t *testing.T) {
+	// Four shapes split in halves twice, below the node holding the unbounded plane
+	shapes := append(randomSpheres(4), Plane{Vec3{0, 1, 0}, 0, nil})
+	nodes, depth := bvhSize(NewBVH(shapes))
+	if nodes != 4 || depth != 3 {
+		t.Errorf("the BVH has %d nodes and is %d deep, want 4 and 3", nodes, depth)
+	}
+	if nodes, depth := bvhSize(NewBVH(randomSpheres(1))); nodes != 0 || depth != 0 {
+		t.Errorf("the BVH of a single shape has %d nodes and is %d deep, want none", nodes, depth)
+	}
+}
+
+func BenchmarkIntersectLinear(b *testing.B) {
+	shapes := randomSpheres(500)
+	rays := randomRays(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		intersectLinear(shapes, rays[i%len(rays)])
+	}
+}
+
+func BenchmarkIntersectBVH(b *testing.B) {
+	bvh := NewBVH(randomSpheres(500))
+	rays := randomRays(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bvh.Intersect(rays[i%len(rays)], 1)
+	}
+}
+
+func TestWorldBoundsSkipsPlanes(t *testing.T) {
+	shapes := []Shape{Sphere{Vec3{3, 2, 5}, 1, nil}, Plane{Vec3{0, 1, 0}, 0, nil}, Sphere{Vec3{0, 0, 0}, 0.5, nil}}
+	min, max := WorldBounds(shapes)
+	if !vecCloseTo(min, Vec3{-0.5, -0.5, -0.5}, 1e-5) || !vecCloseTo(max, Vec3{4, 3, 6}, 1e-5) {
+		t.Errorf("the world bounds are %v to %v, want (-0.5, -0.5, -0.5) to (4, 3, 6)", min, max)
+	}
+	if min, max := WorldBounds(shapes[1:2]); min.X <= max.X {
+		t.Errorf("the world bounds of a plane are %v to %v, want empty", min, max)
+	}
+}
@@ -0,0 +1,117 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+)
+
+// DefaultFieldOfView is the horizontal field of view in degrees of the camera of the built-in scenes
+const DefaultFieldOfView float32 = 90.0
+
+// Camera to shoot rays from
+type Camera struct {
+	Position   Vec3
+	BottomLeft Vec3
+	PixelStepX Vec3
+	PixelStepY Vec3
+	// Rays start on a lens with a radius of LensRadius, spanned by Horizontal and Vertical, and
+	// converge on the plane at FocusDist. A LensRadius of 0 is a pinhole camera.
+	LensRadius float32
+	FocusDist  float32
+	Horizontal Vec3
+	Vertical   Vec3
+	// MotionBlur makes rays sample a random time, so that moving objects smear. Without it all rays are at time 0.
+	MotionBlur bool
+	// Orthographic cameras shoot all rays in Direction, from points spread over the image plane
+	// around Position, so that objects keep their size at any distance
+	Orthographic bool
+	Direction    Vec3
+}
+
+// NewCamera creates a camera at cameraPos looking at cameraTarget, with a horizontal field of view of fov degrees,
+// for an image of the size in cfg. Objects at focusDist are in focus, or at cameraTarget if focusDist is 0,
+// and the others get blurrier with a larger aperture (the diameter of the lens).
+func NewCamera(cameraPos Vec3, cameraTarget Vec3, up Vec3, fov float32, aperture float32, focusDist float32, cfg *RenderConfig) Camera {
+	cameraDirection := Normalize(Sub(cameraTarget, cameraPos))
+	horizontalDirection := Normalize(Cross(Normalize(up), cameraDirection))
+	verticalDirection := Cross(Normalize(cameraDirection), Normalize(horizontalDirection))
+	halfWidth := float32(math.Tan(float64(Deg2Rad(fov)) / 2.0))
+	halfHeight := halfWidth * float32(cfg.Height) / float32(cfg.Width)
+	pixelStepX := MulScalar(2*halfWidth/float32(cfg.Width-1), horizontalDirection)
+	pixelStepY := MulScalar(2*halfHeight/float32(cfg.Height-1), verticalDirection)
+	bottomLeft := Sub(Sub(cameraDirection, MulScalar(halfWidth, horizontalDirection)), MulScalar(halfHeight, verticalDirection))
+	if focusDist == 0 {
+		focusDist = Sub(cameraTarget, cameraPos).Length()
+	}
+	return Camera{cameraPos, bottomLeft, pixelStepX, pixelStepY, aperture / 2, focusDist, horizontalDirection, verticalDirection, false, false, cameraDirection}
+}
+
+// NewOrthographicCamera creates a camera at cameraPos looking at cameraTarget without perspective, that
+// sees a part of the scene viewHeight high and as wide as the aspect ratio of the image in cfg
+func NewOrthographicCamera(cameraPos Vec3, cameraTarget Vec3, up Vec3, viewHeight float32, cfg *RenderConfig) Camera {
+	cameraDirection := Normalize(Sub(cameraTarget, cameraPos))
+	horizontalDirection := Normalize(Cross(Normalize(up), cameraDirection))
+	verticalDirection := Cross(cameraDirection, horizontalDirection)
+	halfHeight := viewHeight / 2
+	halfWidth := halfHeight * float32(cfg.Width) / float32(cfg.Height)
+	pixelStepX := MulScalar(2*halfWidth/float32(cfg.Width-1), horizontalDirection)
+	pixelStepY := MulScalar(2*halfHeight/float32(cfg.Height-1), verticalDirection)
+	bottomLeft := MulScalar(-1, Add(MulScalar(halfWidth, horizontalDirection), MulScalar(halfHeight, verticalDirection)))
+	focusDist := Sub(cameraTarget, cameraPos).Length()
+	return Camera{cameraPos, bottomLeft, pixelStepX, pixelStepY, 0, focusDist, horizontalDirection, verticalDirection, false, true, cameraDirection}
+}
+
+// AutoFrameCamera creates a camera that looks along the Z axis, like the camera of the built-in scenes,
+// at the center of the WorldBounds of the shapes, from just far enough away to see all of them
+func AutoFrameCamera(shapes []Shape, up Vec3, fov float32, cfg *RenderConfig) Camera {
+	min, max := WorldBounds(shapes)
+	if min.X > max.X {
+		return NewCamera(Vec3{0, 0, 0}, Vec3{0, 0, 1}, up, fov, 0, 0, cfg)
+	}
+	center := DivScalar(2, Add(min, max))
+	radius := Sub(max, min).Length() / 2
+	// The sphere around the box fits in the narrower of the horizontal and vertical field of view
+	halfWidth := math.Tan(float64(Deg2Rad(fov)) / 2)
+	halfAngle := math.Atan(math.Min(halfWidth, halfWidth*float64(cfg.Height)/float64(cfg.Width)))
+	distance := radius / float32(math.Sin(halfAngle))
+	return NewCamera(Sub(center, Vec3{0, 0, distance}), center, up, fov, 0, 0, cfg)
+}
+
+func (camera *Camera) getRay(x float32, y float32, rng *rand.Rand) Ray {
+	direction := Add(Add(camera.BottomLeft, MulScalar(x, camera.PixelStepX)), MulScalar(y, camera.PixelStepY))
+	var time float32
+	if camera.MotionBlur {
+		time = rng.Float32()
+	}
+	if camera.Orthographic {
+		// BottomLeft and the pixel steps span the image plane through Position
+		return Ray{Add(camera.Position, direction), camera.Direction, time}
+	}
+	if camera.LensRadius == 0 {
+		return Ray{camera.Position, Normalize(direction), time}
+	}
+	// direction is one unit long along the view direction, so scaling it gives the point on the focus plane
+	lens := MulScalar(camera.LensRadius, RandomPointInUnitDisk(rng))
+	offset := Add(MulScalar(lens.X, camera.Horizontal), MulScalar(lens.Y, camera.Vertical))
+	return Ray{Add(camera.Position, offset), Normalize(Sub(MulScalar(camera.FocusDist, direction), offset)), time}
+}
+
+// Project returns the pixel (x, y), counted from the top left, at which the camera sees the point p in
+// an image of the size in cfg. It returns false for points behind the camera.
+func (camera *Camera) Project(p Vec3, cfg *RenderConfig) (x float32, y float32, ok bool) {
+	relPos := Sub(p, camera.Position)
+	along := Dot(relPos, camera.Direction)
+	if along <= 0 {
+		return 0, 0, false
+	}
+	// getRay goes from BottomLeft along the pixel steps on the image plane one unit in front of a
+	// perspective camera, or through Position for an orthographic one
+	onPlane := relPos
+	if !camera.Orthographic {
+		onPlane = DivScalar(along, relPos)
+	}
+	offset := Sub(onPlane, camera.BottomLeft)
+	x = Dot(offset, camera.PixelStepX) / camera.PixelStepX.SquaredLength()
+	y = Dot(offset, camera.PixelStepY) / camera.PixelStepY.SquaredLength()
+	return x, float32(cfg.Height-1) - y, true
+}
@@ -0,0 +1,75 @@
+package raytracer
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestOrthographicRaysAreParallel(t *testing.T) {
+	cfg := testConfig()
+	camera := NewOrthographicCamera(Vec3{0, 0, 0}, Vec3{0, 0, 1}, Vec3{0, 1, 0}, 4, &cfg)
+	rng := rand.New(rand.NewSource(1))
+	for _, pixel := range [][2]float32{{0, 0}, {31, 0}, {0, 17}, {31, 17}, {15.5, 8.5}} {
+		ray := camera.getRay(pixel[0], pixel[1], rng)
+		if ray.Direction != (Vec3{0, 0, 1}) {
+			t.Errorf("the ray through pixel %v goes in %v, want the view direction (0, 0, 1)", pixel, ray.Direction)
+		}
+		if ray.Origin.Z != 0 {
+			t.Errorf("the ray through pixel %v starts at %v, off the image plane through the camera", pixel, ray.Origin)
+		}
+	}
+	// The image plane is 4 high
+	bottom, top := camera.getRay(0, 0, rng), camera.getRay(0, 17, rng)
+	if !closeTo(top.Origin.Y-bottom.Origin.Y, 4, 1e-5) {
+		t.Errorf("the rays span %v vertically, want the view height of 4", top.Origin.Y-bottom.Origin.Y)
+	}
+}
+
+func TestOrthographicSizeDoesNotDependOnDepth(t *testing.T) {
+	cfg := testConfig()
+	camera := NewOrthographicCamera(Vec3{0, 0, 0}, Vec3{0, 0, 1}, Vec3{0, 1, 0}, 4, &cfg)
+	rng := rand.New(rand.NewSource(1))
+	// coveredPixels counts the pixels on the middle row that see the sphere
+	coveredPixels := func(sphere Sphere) int {
+		covered := 0
+		for x := 0; x < cfg.Width; x++ {
+			if _, ok := sphere.Intersect(camera.getRay(float32(x), 8.5, rng), 1); ok {
+				covered++
+			}
+		}
+		return covered
+	}
+	near, far := coveredPixels(Sphere{Vec3{0, 0, 3}, 1, nil}), coveredPixels(Sphere{Vec3{0, 0, 30}, 1, nil})
+	if near == 0 || near != far {
+		t.Errorf("the near sphere covers %d pixels and the far one %d, want the same", near, far)
+	}
+}
+
+func TestAutoFrameCameraFitsTheSphere(t *testing.T) {
+	cfg := testConfig()
+	sphere := Sphere{Vec3{3, 2, 5}, 1, nil}
+	camera := AutoFrameCamera([]Shape{sphere}, Vec3{0, 1, 0}, DefaultFieldOfView, &cfg)
+	x, y, ok := camera.Project(sphere.Position, &cfg)
+	if !ok || !closeTo(x, float32(cfg.Width-1)/2, 1e-3) || !closeTo(y, float32(cfg.Height-1)/2, 1e-3) {
+		t.Errorf("the center of the sphere is at pixel (%v, %v) %v, want the center of the image", x, y, ok)
+	}
+	rng := rand.New(rand.NewSource(1))
+	if _, ok := sphere.Intersect(camera.getRay(x, y, rng), 1); !ok {
+		t.Error("the ray through the center of the image missed the sphere")
+	}
+	// None of the pixels on the border see the sphere, so it is entirely in view
+	for px := 0; px < cfg.Width; px++ {
+		for _, py := range []int{0, cfg.Height - 1} {
+			if _, ok := sphere.Intersect(camera.getRay(float32(px), float32(py), rng), 1); ok {
+				t.Fatalf("the sphere is seen at pixel (%d, %d) on the border of the image", px, py)
+			}
+		}
+	}
+	for py := 0; py < cfg.Height; py++ {
+		for _, px := range []int{0, cfg.Width - 1} {
+			if _, ok := sphere.Intersect(camera.getRay(float32(px), float32(py), rng), 1); ok {
+				t.Fatalf("the sphere is seen at pixel (%d, %d) on the border of the image", px, py)
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package raytracer_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jvanvugt/go-raytracer/raytracer"
+)
+
+func ExampleRender() {
+	cfg := raytracer.DefaultRenderConfig()
+	cfg.Width, cfg.Height, cfg.Samples = 16, 9, 4
+	camera := raytracer.NewCamera(raytracer.Vec3{0, 0, 0}, raytracer.Vec3{0, 0, 1}, raytracer.Vec3{0, 1, 0}, raytracer.DefaultFieldOfView, 0, 0, &cfg)
+	sphere := raytracer.Sphere{Position: raytracer.Vec3{0, 0, 3}, Radius: 1, Material: raytracer.Emissive{Color: raytracer.Vec3{1, 0, 0}}}
+	scene := raytracer.NewScene([]raytracer.Shape{sphere}, camera, raytracer.SolidBackground{Color: raytracer.Vec3{0, 0, 0}})
+
+	img := raytracer.Render(context.Background(), scene, cfg)
+	fmt.Println(img.Bounds().Size())
+	fmt.Println(img.NRGBAAt(8, 4), img.NRGBAAt(0, 0))
+	// Output:
+	// (16,9)
+	// {255 0 0 255} {0 0 0 255}
+}
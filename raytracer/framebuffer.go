@@ -0,0 +1,301 @@
+package raytracer
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Framebuffer stores the linear color of every pixel, with row 0 at the top of the image
+type Framebuffer struct {
+	Width  int
+	Height int
+	Pixels []Vec3
+	// M2 is the sum of squared differences from the mean luminance of each pixel over all passes
+	// (Welford's algorithm). It is only updated after TrackVariance has been called.
+	M2 []float32
+}
+
+// NewFramebuffer creates a black framebuffer of the given size
+func NewFramebuffer(width int, height int) *Framebuffer {
+	return &Framebuffer{width, height, make([]Vec3, width*height), nil}
+}
+
+// TrackVariance makes Accumulate keep track of the variance of the passes
+func (fb *Framebuffer) TrackVariance() {
+	fb.M2 = make([]float32, len(fb.Pixels))
+}
+
+// At returns the color of the pixel at (x, y)
+func (fb *Framebuffer) At(x int, y int) Vec3 {
+	return fb.Pixels[y*fb.Width+x]
+}
+
+// Set the color of the pixel at (x, y)
+func (fb *Framebuffer) Set(x int, y int, color Vec3) {
+	fb.Pixels[y*fb.Width+x] = color
+}
+
+// Accumulate averages color into the pixel at (x, y), which already holds the average of pass earlier passes
+func (fb *Framebuffer) Accumulate(x int, y int, color Vec3, pass int) {
+	old := fb.At(x, y)
+	mean := Add(old, DivScalar(float32(pass+1), Sub(color, old)))
+	fb.Set(x, y, mean)
+	if fb.M2 != nil {
+		luminance := Luminance(color)
+		fb.M2[y*fb.Width+x] += (luminance - Luminance(old)) * (luminance - Luminance(mean))
+	}
+}
+
+// StandardError estimates the standard error of the mean luminance after the given number of passes,
+// averaged over all pixels
+func (fb *Framebuffer) StandardError(passes int) float32 {
+	if fb.M2 == nil || passes < 2 {
+		return 0
+	}
+	var sum float64
+	for _, m2 := range fb.M2 {
+		sum += float64(standardError(m2, passes))
+	}
+	return float32(sum / float64(len(fb.M2)))
+}
+
+// PixelStandardError estimates the standard error of the mean luminance of the pixel at (x, y)
+// after n samples (or passes) have been accumulated into it
+func (fb *Framebuffer) PixelStandardError(x int, y int, n int) float32 {
+	if fb.M2 == nil || n < 2 {
+		return 0
+	}
+	return standardError(fb.M2[y*fb.Width+x], n)
+}
+
+func standardError(m2 float32, n int) float32 {
+	variance := m2 / float32(n-1)
+	return Sqrt(variance / float32(n))
+}
+
+// Copy the framebuffer
+func (fb *Framebuffer) Copy() *Framebuffer {
+	copied := NewFramebuffer(fb.Width, fb.Height)
+	copy(copied.Pixels, fb.Pixels)
+	if fb.M2 != nil {
+		copied.M2 = append([]float32(nil), fb.M2...)
+	}
+	return copied
+}
+
+// Downsample averages every block of factor by factor pixels into one pixel, in linear color so that
+// gamma correction afterwards doesn't darken edges. Pixels beyond the last whole block are dropped.
+func (fb *Framebuffer) Downsample(factor int) *Framebuffer {
+	small := NewFramebuffer(fb.Width/factor, fb.Height/factor)
+	for y := 0; y < small.Height; y++ {
+		for x := 0; x < small.Width; x++ {
+			sum := Vec3{0, 0, 0}
+			for dy := 0; dy < factor; dy++ {
+				for dx := 0; dx < factor; dx++ {
+					sum = Add(sum, fb.At(x*factor+dx, y*factor+dy))
+				}
+			}
+			small.Set(x, y, DivScalar(float32(factor*factor), sum))
+		}
+	}
+	return small
+}
+
+// RMSE computes the root mean squared difference over all channels of two framebuffers of the same size
+func RMSE(a *Framebuffer, b *Framebuffer) float32 {
+	var sum float64
+	for i := range a.Pixels {
+		diff := Sub(a.Pixels[i], b.Pixels[i])
+		sum += float64(diff.SquaredLength())
+	}
+	return float32(math.Sqrt(sum / float64(3*len(a.Pixels))))
+}
+
+// Exposed returns a copy of the framebuffer with its exposure changed by ev stops
+func (fb *Framebuffer) Exposed(ev float32) *Framebuffer {
+	exposed := NewFramebuffer(fb.Width, fb.Height)
+	scale := float32(math.Pow(2, float64(ev)))
+	for i, pixel := range fb.Pixels {
+		exposed.Pixels[i] = MulScalar(scale, pixel)
+	}
+	return exposed
+}
+
+// ToneMapped returns a copy of the framebuffer with ReinhardToneMap applied to every pixel
+func (fb *Framebuffer) ToneMapped() *Framebuffer {
+	mapped := NewFramebuffer(fb.Width, fb.Height)
+	for i, pixel := range fb.Pixels {
+		mapped.Pixels[i] = ReinhardToneMap(pixel)
+	}
+	return mapped
+}
+
+// Heatmap colors the pixels by their value relative to the largest value in the framebuffer, from blue
+// for zero through green to red for the largest, like the costs rendered in CostMode
+func (fb *Framebuffer) Heatmap() *Framebuffer {
+	var highest float32
+	for _, pixel := range fb.Pixels {
+		highest = Max(highest, pixel.X)
+	}
+	heatmap := NewFramebuffer(fb.Width, fb.Height)
+	if highest == 0 {
+		return heatmap
+	}
+	blue, green, red := Vec3{0, 0, 1}, Vec3{0, 1, 0}, Vec3{1, 0, 0}
+	for i, pixel := range fb.Pixels {
+		t := pixel.X / highest
+		if t < 0.5 {
+			heatmap.Pixels[i] = Lerp(blue, green, 2*t)
+		} else {
+			heatmap.Pixels[i] = Lerp(green, red, 2*t-1)
+		}
+	}
+	return heatmap
+}
+
+// AutoExposure picks the change in exposure, in stops, that brings the median luminance of the
+// framebuffer to key
+func (fb *Framebuffer) AutoExposure(key float32) float32 {
+	luminances := make([]float32, len(fb.Pixels))
+	for i, pixel := range fb.Pixels {
+		luminances[i] = Luminance(pixel)
+	}
+	m := median(luminances)
+	if m <= 0 {
+		return 0
+	}
+	return float32(math.Log2(float64(key / m)))
+}
+
+// Contrast of the pixel at (x, y) with its neighbours: (max - min) / (max + min) of the luminances
+// in the surrounding 3x3 block
+func (fb *Framebuffer) Contrast(x int, y int) float32 {
+	lo := float32(math.MaxFloat32)
+	hi := float32(0)
+	for ny := y - 1; ny <= y+1; ny++ {
+		for nx := x - 1; nx <= x+1; nx++ {
+			if nx < 0 || ny < 0 || nx >= fb.Width || ny >= fb.Height {
+				continue
+			}
+			luminance := Luminance(fb.At(nx, ny))
+			lo = Min(lo, luminance)
+			hi = Max(hi, luminance)
+		}
+	}
+	if hi <= 0 {
+		return 0
+	}
+	return (hi - lo) / (hi + lo)
+}
+
+// EdgeMap is white where the contrast of a pixel is above threshold, and black elsewhere
+func (fb *Framebuffer) EdgeMap(threshold float32) *Framebuffer {
+	edges := NewFramebuffer(fb.Width, fb.Height)
+	for y := 0; y < fb.Height; y++ {
+		for x := 0; x < fb.Width; x++ {
+			if fb.Contrast(x, y) > threshold {
+				edges.Set(x, y, Vec3{1, 1, 1})
+			}
+		}
+	}
+	return edges
+}
+
+// Despeckle removes fireflies: every pixel whose luminance is more than threshold times the median
+// luminance of its neighbours gets replaced by the median of its neighbours. It returns the number of
+// replaced pixels.
+func (fb *Framebuffer) Despeckle(threshold float32) int {
+	source := fb.Copy()
+	replaced := 0
+	var reds, greens, blues, luminances []float32
+	for y := 0; y < fb.Height; y++ {
+		for x := 0; x < fb.Width; x++ {
+			reds, greens, blues, luminances = reds[:0], greens[:0], blues[:0], luminances[:0]
+			for ny := y - 1; ny <= y+1; ny++ {
+				for nx := x - 1; nx <= x+1; nx++ {
+					if (nx == x && ny == y) || nx < 0 || ny < 0 || nx >= fb.Width || ny >= fb.Height {
+						continue
+					}
+					neighbour := source.At(nx, ny)
+					reds = append(reds, neighbour.X)
+					greens = append(greens, neighbour.Y)
+					blues = append(blues, neighbour.Z)
+					luminances = append(luminances, Luminance(neighbour))
+				}
+			}
+			if len(luminances) == 0 || Luminance(source.At(x, y)) <= threshold*median(luminances) {
+				continue
+			}
+			fb.Set(x, y, Vec3{median(reds), median(greens), median(blues)})
+			replaced++
+		}
+	}
+	return replaced
+}
+
+// AddGrain adds monochromatic film grain with a standard deviation of intensity to every pixel,
+// clamping the result at black. With shadows set, darker pixels get more grain than bright ones. The
+// grain is generated from its own fixed seed so it is the same every time.
+func (fb *Framebuffer) AddGrain(intensity float32, shadows bool) {
+	rng := rand.New(rand.NewSource(0))
+	for i, pixel := range fb.Pixels {
+		amount := intensity
+		if shadows {
+			amount *= 1 - Min(Luminance(pixel), 1)
+		}
+		noise := float32(rng.NormFloat64()) * amount
+		fb.Pixels[i] = Vec3{
+			Max(0, pixel.X+noise),
+			Max(0, pixel.Y+noise),
+			Max(0, pixel.Z+noise),
+		}
+	}
+}
+
+// median sorts values and returns the middle one
+func median(values []float32) float32 {
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values[len(values)/2]
+}
+
+// Image converts the framebuffer to an 8-bit image, gamma corrected for a display with the given gamma.
+// A gamma of 1 leaves the colors linear.
+func (fb *Framebuffer) Image(gamma float32) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, fb.Width, fb.Height))
+	for y := 0; y < fb.Height; y++ {
+		for x := 0; x < fb.Width; x++ {
+			img.Set(x, y, GammaCorrect(fb.At(x, y), gamma).RGBA())
+		}
+	}
+	return img
+}
+
+// Image16 converts the framebuffer to a 16-bit image, gamma corrected like Image
+func (fb *Framebuffer) Image16(gamma float32) *image.NRGBA64 {
+	img := image.NewNRGBA64(image.Rect(0, 0, fb.Width, fb.Height))
+	for y := 0; y < fb.Height; y++ {
+		for x := 0; x < fb.Width; x++ {
+			img.Set(x, y, GammaCorrect(fb.At(x, y), gamma).RGBA64())
+		}
+	}
+	return img
+}
+
+// WritePPM writes an image as a binary PPM (P6), ignoring its alpha channel
+func WritePPM(w io.Writer, img *image.NRGBA) error {
+	bounds := img.Bounds()
+	buffered := bufio.NewWriter(w)
+	fmt.Fprintf(buffered, "P6 %d %d 255\n", bounds.Dx(), bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := img.NRGBAAt(x, y)
+			buffered.Write([]byte{pixel.R, pixel.G, pixel.B})
+		}
+	}
+	return buffered.Flush()
+}
@@ -0,0 +1,90 @@
+package raytracer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+)
+
+func TestWritePPMRoundTrip(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 10, 20, 128})
+	img.SetNRGBA(2, 1, color.NRGBA{1, 2, 3, 255})
+	var buffer bytes.Buffer
+	if err := WritePPM(&buffer, img); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(&buffer)
+	var width, height, maxValue int
+	if _, err := fmt.Fscanf(reader, "P6 %d %d %d\n", &width, &height, &maxValue); err != nil {
+		t.Fatal("could not parse the header: ", err)
+	}
+	if width != 3 || height != 2 || maxValue != 255 {
+		t.Fatalf("the header is for a %dx%d image up to %d, want 3x2 up to 255", width, height, maxValue)
+	}
+	pixels, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pixels) != 3*width*height {
+		t.Fatalf("PPM has %d bytes of pixels, want %d", len(pixels), 3*width*height)
+	}
+	// Rows go from the top down like in the image, without the alpha channel
+	if got := pixels[:3]; !bytes.Equal(got, []byte{255, 10, 20}) {
+		t.Errorf("the top left pixel is %v, want [255 10 20]", got)
+	}
+	if got := pixels[len(pixels)-3:]; !bytes.Equal(got, []byte{1, 2, 3}) {
+		t.Errorf("the bottom right pixel is %v, want [1 2 3]", got)
+	}
+}
+
+func TestDownsampleKeepsASolidColor(t *testing.T) {
+	c := Vec3{0.2, 0.4, 0.6}
+	fb := NewFramebuffer(8, 6)
+	for i := range fb.Pixels {
+		fb.Pixels[i] = c
+	}
+	small := fb.Downsample(2)
+	if small.Width != 4 || small.Height != 3 {
+		t.Fatalf("downsampling 8x6 by 2 gave %dx%d, want 4x3", small.Width, small.Height)
+	}
+	for _, pixel := range small.Pixels {
+		if !vecCloseTo(pixel, c, 1e-6) {
+			t.Fatalf("a pixel of the downsampled solid color is %v, want %v", pixel, c)
+		}
+	}
+	if got, want := small.Image(2).At(1, 1), fb.Image(2).At(2, 2); got != want {
+		t.Errorf("the downsampled image has color %v, the full size one %v", got, want)
+	}
+}
+
+func TestDownsampleAveragesLinearColors(t *testing.T) {
+	// Black and white pixels average to half the light, because the framebuffer is not gamma corrected yet
+	fb := NewFramebuffer(2, 2)
+	fb.Set(0, 0, Vec3{1, 1, 1})
+	fb.Set(1, 1, Vec3{1, 1, 1})
+	if got := fb.Downsample(2).At(0, 0); !vecCloseTo(got, Vec3{0.5, 0.5, 0.5}, 1e-6) {
+		t.Errorf("downsampling a checkerboard gave %v, want (0.5, 0.5, 0.5)", got)
+	}
+}
+
+func TestGrainShowsInTheShadows(t *testing.T) {
+	fb := NewFramebuffer(16, 16)
+	fb.AddGrain(0.1, true)
+	mean := meanLuminance(fb)
+	var variance float32
+	for _, pixel := range fb.Pixels {
+		if pixel.X != pixel.Y || pixel.X != pixel.Z {
+			t.Fatalf("a pixel of the grain is %v, want it gray", pixel)
+		}
+		variance += (pixel.X - mean) * (pixel.X - mean)
+	}
+	if variance /= float32(len(fb.Pixels)); variance <= 0 {
+		t.Errorf("the grain on a black image has variance %v, want it positive", variance)
+	}
+}
@@ -0,0 +1,126 @@
+package raytracer
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"image/draw"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LUT is a 3D color lookup table, as used by color grading tools
+type LUT struct {
+	Size      int
+	DomainMin Vec3
+	DomainMax Vec3
+	// Table holds Size^3 colors with the red index changing fastest, then green, then blue
+	Table []Vec3
+}
+
+// LoadCubeLUT reads a 3D LUT in the .cube format
+func LoadCubeLUT(path string) (*LUT, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lut := &LUT{DomainMin: Vec3{0, 0, 0}, DomainMax: Vec3{1, 1, 1}}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch fields[0] {
+		case "TITLE":
+		case "LUT_1D_SIZE":
+			return nil, fmt.Errorf("%s: 1D LUTs are not supported", path)
+		case "LUT_3D_SIZE":
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%s:%d: expected a single size", path, line)
+			}
+			lut.Size, err = strconv.Atoi(fields[1])
+			if err != nil || lut.Size < 2 {
+				return nil, fmt.Errorf("%s:%d: invalid LUT size %q", path, line, fields[1])
+			}
+		case "DOMAIN_MIN":
+			lut.DomainMin, err = parseCubeColor(fields[1:])
+		case "DOMAIN_MAX":
+			lut.DomainMax, err = parseCubeColor(fields[1:])
+		default:
+			var entry Vec3
+			entry, err = parseCubeColor(fields)
+			lut.Table = append(lut.Table, entry)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lut.Size == 0 {
+		return nil, fmt.Errorf("%s: missing LUT_3D_SIZE", path)
+	}
+	if len(lut.Table) != lut.Size*lut.Size*lut.Size {
+		return nil, fmt.Errorf("%s: expected %d entries, got %d", path, lut.Size*lut.Size*lut.Size, len(lut.Table))
+	}
+	return lut, nil
+}
+
+func parseCubeColor(fields []string) (Vec3, error) {
+	if len(fields) != 3 {
+		return Vec3{}, fmt.Errorf("expected 3 values, got %d", len(fields))
+	}
+	var values [3]float32
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			return Vec3{}, err
+		}
+		values[i] = float32(value)
+	}
+	return Vec3{values[0], values[1], values[2]}, nil
+}
+
+func (lut *LUT) entry(r int, g int, b int) Vec3 {
+	return lut.Table[(b*lut.Size+g)*lut.Size+r]
+}
+
+// lutCoordinate maps a channel to its position in the table, split into a cell and the offset inside it
+func (lut *LUT) lutCoordinate(value float32, min float32, max float32) (int, float32) {
+	position := (value - min) / (max - min) * float32(lut.Size-1)
+	position = Min(Max(position, 0), float32(lut.Size-1))
+	cell := int(position)
+	if cell == lut.Size-1 {
+		cell--
+	}
+	return cell, position - float32(cell)
+}
+
+// Apply looks up a color in the LUT, interpolating trilinearly between the table entries
+func (lut *LUT) Apply(c Vec3) Vec3 {
+	r, dr := lut.lutCoordinate(c.X, lut.DomainMin.X, lut.DomainMax.X)
+	g, dg := lut.lutCoordinate(c.Y, lut.DomainMin.Y, lut.DomainMax.Y)
+	b, db := lut.lutCoordinate(c.Z, lut.DomainMin.Z, lut.DomainMax.Z)
+	c00 := Lerp(lut.entry(r, g, b), lut.entry(r+1, g, b), dr)
+	c10 := Lerp(lut.entry(r, g+1, b), lut.entry(r+1, g+1, b), dr)
+	c01 := Lerp(lut.entry(r, g, b+1), lut.entry(r+1, g, b+1), dr)
+	c11 := Lerp(lut.entry(r, g+1, b+1), lut.entry(r+1, g+1, b+1), dr)
+	return Lerp(Lerp(c00, c10, dg), Lerp(c01, c11, dg), db)
+}
+
+// ApplyToImage grades every pixel of an (already gamma corrected) image
+func (lut *LUT) ApplyToImage(img draw.Image) {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+			graded := lut.Apply(Vec3{float32(pixel.R) / 65535, float32(pixel.G) / 65535, float32(pixel.B) / 65535})
+			img.Set(x, y, graded.RGBA64())
+		}
+	}
+}
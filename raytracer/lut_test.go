@@ -0,0 +1,55 @@
+package raytracer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCube writes the lines of a .cube file to a temporary file and returns its path
+func writeCube(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.cube")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// identityEntries are the entries of a 2x2x2 LUT that leaves every color unchanged
+var identityEntries = []string{
+	"0 0 0", "1 0 0", "0 1 0", "1 1 0",
+	"0 0 1", "1 0 1", "0 1 1", "1 1 1",
+}
+
+func TestIdentityLUTKeepsTheColors(t *testing.T) {
+	path := writeCube(t, append([]string{"TITLE \"identity\"", "# red changes fastest", "LUT_3D_SIZE 2"}, identityEntries...)...)
+	lut, err := LoadCubeLUT(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []Vec3{{0, 0, 0}, {1, 1, 1}, {0.2, 0.5, 0.8}, {0.9, 0.1, 0.4}} {
+		if got := lut.Apply(c); !vecCloseTo(got, c, 1e-6) {
+			t.Errorf("the identity LUT maps %v to %v", c, got)
+		}
+	}
+}
+
+func TestLoadCubeLUTErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"missing size", identityEntries, "missing LUT_3D_SIZE"},
+		{"wrong entry count", append([]string{"LUT_3D_SIZE 2"}, identityEntries[:7]...), "expected 8 entries, got 7"},
+		{"1D", []string{"LUT_1D_SIZE 2", "0 0 0", "1 1 1"}, "1D LUTs are not supported"},
+	}
+	for _, test := range tests {
+		_, err := LoadCubeLUT(writeCube(t, test.lines...))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.want)
+		}
+	}
+}
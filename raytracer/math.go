@@ -0,0 +1,253 @@
+package raytracer
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+// Vec3 represents a 3D vector
+type Vec3 struct {
+	X float32
+	Y float32
+	Z float32
+}
+
+// Pi is the circle constant
+const Pi = float32(math.Pi)
+
+func (v Vec3) String() string {
+	return fmt.Sprintf("(%f, %f, %f)", v.X, v.Y, v.Z)
+}
+
+// Component returns the X, Y or Z component of the vector for axis 0, 1 or 2
+func (v Vec3) Component(axis int) float32 {
+	switch axis {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	}
+	return v.Z
+}
+
+// RGBA interpretation of the vector, with each channel clamped to [0, 1]
+func (v Vec3) RGBA() color.Color {
+	c := Clamp(v, 0, 1)
+	return color.RGBA{uint8(c.X * 255), uint8(c.Y * 255), uint8(c.Z * 255), 255}
+}
+
+// GammaCorrect raises every channel of a linear color to the power 1 / gamma, for a display with that gamma
+func GammaCorrect(color Vec3, gamma float32) Vec3 {
+	exponent := 1 / float64(gamma)
+	return Vec3{
+		float32(math.Pow(float64(color.X), exponent)),
+		float32(math.Pow(float64(color.Y), exponent)),
+		float32(math.Pow(float64(color.Z), exponent)),
+	}
+}
+
+// Luminance of a linear RGB color
+func Luminance(v Vec3) float32 {
+	return 0.2126*v.X + 0.7152*v.Y + 0.0722*v.Z
+}
+
+// RGBA64 interpretation of the vector, with each channel clamped to [0, 1]
+func (v Vec3) RGBA64() color.Color {
+	c := Clamp(v, 0, 1)
+	return color.NRGBA64{uint16(c.X*65535 + 0.5), uint16(c.Y*65535 + 0.5), uint16(c.Z*65535 + 0.5), 65535}
+}
+
+// ClampLuminance scales a color down so that its luminance is at most max, keeping its hue
+func ClampLuminance(v Vec3, max float32) Vec3 {
+	luminance := Luminance(v)
+	if luminance <= max {
+		return v
+	}
+	return MulScalar(max/luminance, v)
+}
+
+// ReinhardToneMap maps each channel c of an HDR color to c / (1 + c), which compresses highlights
+// into [0, 1) gradually instead of clipping them. Negative channels become 0.
+func ReinhardToneMap(color Vec3) Vec3 {
+	toneMap := func(c float32) float32 {
+		c = Max(c, 0)
+		return c / (1 + c)
+	}
+	return Vec3{toneMap(color.X), toneMap(color.Y), toneMap(color.Z)}
+}
+
+// SquaredLength of the vector
+func (v Vec3) SquaredLength() float32 {
+	return Dot(v, v)
+}
+
+// Length of the vector
+func (v Vec3) Length() float32 {
+	return Sqrt(v.SquaredLength())
+}
+
+// Dot product between two vectors
+func Dot(a Vec3, b Vec3) float32 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+// Cross product between two vectors
+func Cross(a Vec3, b Vec3) Vec3 {
+	return Vec3{
+		a.Y*b.Z - a.Z*b.Y,
+		a.Z*b.X - a.X*b.Z,
+		a.X*b.Y - a.Y*b.X,
+	}
+}
+
+// Normalize a vector. The zero vector has no direction, so it stays the zero vector
+func Normalize(a Vec3) Vec3 {
+	normalized, ok := SafeDivScalar(a.Length(), a)
+	if !ok {
+		return Vec3{0, 0, 0}
+	}
+	return normalized
+}
+
+// Sub computes a - b
+func Sub(a Vec3, b Vec3) Vec3 {
+	return Vec3{a.X - b.X, a.Y - b.Y, a.Z - b.Z}
+}
+
+// Add computes a + b
+func Add(a Vec3, b Vec3) Vec3 {
+	return Vec3{a.X + b.X, a.Y + b.Y, a.Z + b.Z}
+}
+
+// AddScalar computes s + a
+func AddScalar(s float32, a Vec3) Vec3 {
+	return Vec3{a.X + s, a.Y + s, a.Z + s}
+}
+
+// MulScalar computes s * a
+func MulScalar(s float32, a Vec3) Vec3 {
+	return Vec3{a.X * s, a.Y * s, a.Z * s}
+}
+
+// DivScalar computes a / s
+func DivScalar(s float32, a Vec3) Vec3 {
+	return Vec3{a.X / s, a.Y / s, a.Z / s}
+}
+
+// divisionEpsilon is the magnitude below which SafeDivScalar refuses to divide
+const divisionEpsilon = 1e-20
+
+// SafeDivScalar computes a / s, or returns false when s is (almost) zero
+func SafeDivScalar(s float32, a Vec3) (Vec3, bool) {
+	if Abs(s) < divisionEpsilon {
+		return Vec3{}, false
+	}
+	return DivScalar(s, a), true
+}
+
+// Mul computes the elementwise product between two vectors
+func Mul(a Vec3, b Vec3) Vec3 {
+	return Vec3{a.X * b.X, a.Y * b.Y, a.Z * b.Z}
+}
+
+// Lerp interpolates linearly from a at t = 0 to b at t = 1
+func Lerp(a Vec3, b Vec3, t float32) Vec3 {
+	return Add(a, MulScalar(t, Sub(b, a)))
+}
+
+// Clamp every channel of v to [lo, hi]
+func Clamp(v Vec3, lo float32, hi float32) Vec3 {
+	return Vec3{Min(Max(v.X, lo), hi), Min(Max(v.Y, lo), hi), Min(Max(v.Z, lo), hi)}
+}
+
+// Reflect the incoming direction in the surface with the given unit normal
+func Reflect(incoming Vec3, normal Vec3) Vec3 {
+	return Sub(incoming, MulScalar(2.0*Dot(normal, incoming), normal))
+}
+
+// RandomUniform sample a random number in [-1, 1)
+func RandomUniform(rng *rand.Rand) float32 {
+	return rng.Float32()*2 - 1
+}
+
+// RandomPointInUnitSphere samples a random point inside the unit sphere
+func RandomPointInUnitSphere(rng *rand.Rand) Vec3 {
+	for {
+		v := Vec3{RandomUniform(rng), RandomUniform(rng), RandomUniform(rng)}
+		if v.SquaredLength() <= 1 {
+			return v
+		}
+	}
+}
+
+// RandomPointInUnitDisk samples a random point inside the unit disk in the XY plane
+func RandomPointInUnitDisk(rng *rand.Rand) Vec3 {
+	for {
+		v := Vec3{RandomUniform(rng), RandomUniform(rng), 0}
+		if v.SquaredLength() <= 1 {
+			return v
+		}
+	}
+}
+
+// OrthonormalBasis returns two unit vectors that together with the unit vector n form an orthonormal basis
+func OrthonormalBasis(n Vec3) (Vec3, Vec3) {
+	helper := Vec3{1, 0, 0}
+	if Abs(n.X) > 0.9 {
+		helper = Vec3{0, 1, 0}
+	}
+	tangent := Normalize(Cross(helper, n))
+	return tangent, Cross(n, tangent)
+}
+
+// RandomDirectionInHemisphere samples a direction uniformly over the hemisphere around the unit vector normal
+func RandomDirectionInHemisphere(normal Vec3, rng *rand.Rand) Vec3 {
+	cosTheta := rng.Float32()
+	sinTheta := Sqrt(1 - cosTheta*cosTheta)
+	phi := 2 * Pi * rng.Float32()
+	tangent, bitangent := OrthonormalBasis(normal)
+	x := sinTheta * float32(math.Cos(float64(phi)))
+	y := sinTheta * float32(math.Sin(float64(phi)))
+	return Add(Add(MulScalar(x, tangent), MulScalar(y, bitangent)), MulScalar(cosTheta, normal))
+}
+
+// Sqrt computes the sqrt of a float32
+func Sqrt(x float32) float32 {
+	return float32(math.Sqrt(float64(x)))
+}
+
+// Deg2Rad converts an angle in degrees to radians
+func Deg2Rad(angle float32) float32 {
+	return angle / 180 * Pi
+}
+
+// Rad2Deg converts an angle in radians to degrees
+func Rad2Deg(angle float32) float32 {
+	return angle * 180 / Pi
+}
+
+// Abs computes the absolute value of a float32
+func Abs(x float32) float32 {
+	if x > 0 {
+		return x
+	}
+	return -x
+}
+
+// Max returns the larger of two float32s
+func Max(a float32, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Min returns the smaller of two float32s
+func Min(a float32, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,145 @@
+package raytracer
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestRGBAClampsChannels(t *testing.T) {
+	if got, want := (Vec3{2, -0.5, 1}).RGBA(), (color.RGBA{255, 0, 255, 255}); got != want {
+		t.Errorf("Vec3{2, -0.5, 1}.RGBA() = %v, want %v", got, want)
+	}
+	if got, want := (Vec3{1.2, 1.2, 1.2}).RGBA(), (color.RGBA{255, 255, 255, 255}); got != want {
+		t.Errorf("Vec3{1.2, 1.2, 1.2}.RGBA() = %v, want %v", got, want)
+	}
+}
+
+func TestSafeDivScalar(t *testing.T) {
+	tests := []struct {
+		s    float32
+		want Vec3
+		ok   bool
+	}{
+		{2, Vec3{1, -2, 0.5}, true},
+		{-2, Vec3{-1, 2, -0.5}, true},
+		{0, Vec3{}, false},
+		{float32(math.Copysign(0, -1)), Vec3{}, false},
+		{1e-30, Vec3{}, false},
+	}
+	for _, test := range tests {
+		got, ok := SafeDivScalar(test.s, Vec3{2, -4, 1})
+		if got != test.want || ok != test.ok {
+			t.Errorf("SafeDivScalar(%v, (2, -4, 1)) = %v, %v, want %v, %v", test.s, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestNormalizeZeroVector(t *testing.T) {
+	if got := Normalize(Vec3{0, 0, 0}); got != (Vec3{0, 0, 0}) {
+		t.Errorf("Normalize of the zero vector is %v, want the zero vector", got)
+	}
+	if got := Normalize(Vec3{0, 3, 4}); got != (Vec3{0, 0.6, 0.8}) {
+		t.Errorf("Normalize((0, 3, 4)) = %v, want (0, 0.6, 0.8)", got)
+	}
+}
+
+func TestScatterDirectionFallsBackOnZero(t *testing.T) {
+	normal := Vec3{0, 1, 0}
+	if got := scatterDirection(Vec3{0, 0, 0}, normal); got != normal {
+		t.Errorf("scatterDirection of the zero vector is %v, want the fallback %v", got, normal)
+	}
+}
+
+func TestReinhardToneMap(t *testing.T) {
+	bright := ReinhardToneMap(Vec3{10, 10, 10})
+	if bright.X < 0.9 || bright.X >= 1 || bright.Y != bright.X || bright.Z != bright.X {
+		t.Errorf("ReinhardToneMap((10, 10, 10)) = %v, want just below 1", bright)
+	}
+	if got := ReinhardToneMap(Vec3{-1, 0, 1}); got != (Vec3{0, 0, 0.5}) {
+		t.Errorf("ReinhardToneMap((-1, 0, 1)) = %v, want (0, 0, 0.5)", got)
+	}
+}
+
+func TestLerp(t *testing.T) {
+	a, b := Vec3{0, 2, -1}, Vec3{4, 2, 1}
+	tests := []struct {
+		t    float32
+		want Vec3
+	}{
+		{0, a},
+		{1, b},
+		{0.5, Vec3{2, 2, 0}},
+		{-1, Vec3{-4, 2, -3}},
+		{2, Vec3{8, 2, 3}},
+	}
+	for _, test := range tests {
+		if got := Lerp(a, b, test.t); got != test.want {
+			t.Errorf("Lerp(%v, %v, %v) = %v, want %v", a, b, test.t, got, test.want)
+		}
+	}
+}
+
+func TestClamp(t *testing.T) {
+	tests := []struct {
+		v    Vec3
+		want Vec3
+	}{
+		{Vec3{0.5, 0.2, 0.9}, Vec3{0.5, 0.2, 0.9}},
+		{Vec3{-1, 2, 0}, Vec3{0, 1, 0}},
+		{Vec3{0, 1, 1}, Vec3{0, 1, 1}},
+		{Vec3{float32(math.Inf(1)), float32(math.Inf(-1)), 1e30}, Vec3{1, 0, 1}},
+	}
+	for _, test := range tests {
+		if got := Clamp(test.v, 0, 1); got != test.want {
+			t.Errorf("Clamp(%v, 0, 1) = %v, want %v", test.v, got, test.want)
+		}
+	}
+}
+
+func TestReflect(t *testing.T) {
+	normal := Vec3{0, 1, 0}
+	tests := []struct {
+		incoming Vec3
+		want     Vec3
+	}{
+		{Vec3{1, -1, 0}, Vec3{1, 1, 0}},
+		{Vec3{0, -1, 0}, Vec3{0, 1, 0}},
+		{Vec3{1, 0, 0}, Vec3{1, 0, 0}},
+		{Vec3{0, 1, 2}, Vec3{0, -1, 2}},
+		{Vec3{0, 0, 0}, Vec3{0, 0, 0}},
+	}
+	for _, test := range tests {
+		if got := Reflect(test.incoming, normal); got != test.want {
+			t.Errorf("Reflect(%v, %v) = %v, want %v", test.incoming, normal, got, test.want)
+		}
+	}
+}
+
+func TestGammaCorrect(t *testing.T) {
+	for _, c := range []Vec3{{0, 0.25, 1}, {0.01, 0.5, 0.9}, {2, 4, 0.0001}} {
+		want := Vec3{Sqrt(c.X), Sqrt(c.Y), Sqrt(c.Z)}
+		if got := GammaCorrect(c, 2); !vecCloseTo(got, want, 1e-6) {
+			t.Errorf("GammaCorrect(%v, 2) = %v, want the square roots %v", c, got, want)
+		}
+		if got := GammaCorrect(c, 1); got != c {
+			t.Errorf("GammaCorrect(%v, 1) = %v, want it unchanged", c, got)
+		}
+	}
+}
+
+func TestClampLuminance(t *testing.T) {
+	clamped := ClampLuminance(Vec3{50, 0, 0}, 2)
+	if !closeTo(Luminance(clamped), 2, 1e-5) || clamped.X <= 0 || clamped.Y != 0 || clamped.Z != 0 {
+		t.Errorf("ClampLuminance((50, 0, 0), 2) = %v with luminance %v, want only red with luminance 2", clamped, Luminance(clamped))
+	}
+	// The hue stays the same
+	bright := Vec3{30, 20, 10}
+	clamped = ClampLuminance(bright, 1)
+	if !closeTo(Luminance(clamped), 1, 1e-5) || !vecCloseTo(MulScalar(clamped.X/bright.X, bright), clamped, 1e-5) {
+		t.Errorf("ClampLuminance(%v, 1) = %v, want it scaled to luminance 1", bright, clamped)
+	}
+	if dim := (Vec3{0.1, 0.2, 0.3}); ClampLuminance(dim, 1) != dim {
+		t.Errorf("ClampLuminance(%v, 1) = %v, want it unchanged", dim, ClampLuminance(dim, 1))
+	}
+}
@@ -0,0 +1,640 @@
+// Package raytracer renders scenes of spheres, planes and other shapes with a path tracer.
+package raytracer
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const spawnEpsilon = 1e-5
+
+// RenderConfig holds the size of the image, how much work goes into every pixel and how the light
+// is gathered. Start from DefaultRenderConfig, because the zero value of some fields disables them.
+type RenderConfig struct {
+	Width   int
+	Height  int
+	Samples int
+	Bounces int
+	// Tiles is the number of parts the image is split into to render them in parallel
+	Tiles int
+	// MaxDiffuse and MaxSpecular (mirror and glass) limit the number of bounces of those kinds, if not negative
+	MaxDiffuse  int
+	MaxSpecular int
+	// SkyLighting lets the background light the scene instead of only showing behind it
+	SkyLighting bool
+	// BackgroundScale multiplies the background where the camera sees it directly
+	BackgroundScale float32
+	// Antithetic pairs every sample with one mirrored around the pixel center
+	Antithetic bool
+	// SpawnOffset moves bounced rays off the surface, see spawnOrigin
+	SpawnOffset float32
+	// IndirectClamp limits the luminance of indirect light to remove fireflies, if positive
+	IndirectClamp float32
+	// SampleClamp limits the luminance of every sample to remove fireflies, if positive
+	SampleClamp float32
+	// Roulette is the bounce from which paths get terminated at random (Russian roulette), if positive
+	Roulette int
+	// Foveated concentrates the samples around the pixel Fovea (counted from the top) and takes
+	// fewer towards the edges
+	Foveated bool
+	Fovea    Vec3
+	// Mask restricts rendering to the pixels where it is not black, if it is set
+	Mask image.Image
+	// MinSamplesEdge and MinSamplesInterior are the number of samples Refine takes of every pixel
+	// on high-contrast edges and away from them, regardless of their noise
+	MinSamplesEdge     int
+	MinSamplesInterior int
+	// AdaptiveTolerance stops sampling a pixel once the standard error of its mean luminance is below it,
+	// after at least AdaptiveMinSamples samples, if it is positive. Samples is the maximum then.
+	AdaptiveTolerance  float32
+	AdaptiveMinSamples int
+	// Seed is combined with the pixel coordinates to seed the random numbers of every pixel, so the same
+	// seed always gives the same image and other seeds give other noise
+	Seed int64
+	// Mode selects what the image shows
+	Mode Mode
+	// Stratified spreads the samples of a pixel over a grid, if their number is a square
+	Stratified bool
+	// Filter is the distribution of the samples around the pixel centers
+	Filter Filter
+	// Progress receives the share of the image that is done and the estimated time remaining while
+	// rendering, if it is set
+	Progress io.Writer
+	// Stats counts the rays cast while rendering, if it is set
+	Stats *RenderStats
+	// Gamma of the display Render corrects the image for
+	Gamma float32
+	// WorldScale is the number of scene units per meter. The epsilons of the shapes are tuned for
+	// scenes modelled in meters, so a scene in millimeters should use a WorldScale of 1000 and one in
+	// kilometers a WorldScale of 0.001 to avoid shadow acne or light leaking through thin objects.
+	WorldScale float32
+}
+
+// DefaultRenderConfig is a 1280x720 render with 100 samples and at most 50 bounces per path
+func DefaultRenderConfig() RenderConfig {
+	return RenderConfig{
+		Width:              1280,
+		Height:             720,
+		Samples:            100,
+		Bounces:            50,
+		Tiles:              runtime.NumCPU(),
+		MaxDiffuse:         -1,
+		MaxSpecular:        -1,
+		SkyLighting:        true,
+		BackgroundScale:    1,
+		MinSamplesEdge:     16,
+		MinSamplesInterior: 2,
+		AdaptiveMinSamples: 16,
+		Gamma:              2,
+		WorldScale:         1,
+	}
+}
+
+// Ray from origin in a direction, at a time between 0 and 1 in which the shutter of the camera is open
+type Ray struct {
+	Origin    Vec3
+	Direction Vec3
+	Time      float32
+}
+
+// At computes the point on the ray at t
+func (ray *Ray) At(t float32) Vec3 {
+	return Add(ray.Origin, MulScalar(t, ray.Direction))
+}
+
+// Hit represents data about a ray hitting an object
+type Hit struct {
+	T        float32
+	Position Vec3
+	Normal   Vec3
+	Material Material
+	// U and V are the texture coordinates of the hit, for the shapes that have them
+	U float32
+	V float32
+}
+
+// NewHit creates a Hit object
+func NewHit(t float32, ray Ray, normal Vec3, material Material) Hit {
+	return Hit{
+		t,
+		ray.At(t),
+		normal,
+		material,
+		0,
+		0,
+	}
+}
+
+// InMask reports whether the pixel at (x, y) (counted from the top) should be rendered
+func (cfg *RenderConfig) InMask(x int, y int) bool {
+	if cfg.Mask == nil {
+		return true
+	}
+	r, g, b, _ := cfg.Mask.At(x, y).RGBA()
+	return r != 0 || g != 0 || b != 0
+}
+
+// foveatedSamples scales the number of samples for the pixel at (x, y) (counted from the top) down
+// with the distance from the fovea
+func (cfg *RenderConfig) foveatedSamples(x int, y int, samples int) int {
+	if !cfg.Foveated {
+		return samples
+	}
+	distance := Sub(Vec3{float32(x), float32(y), 0}, cfg.Fovea).Length()
+	radius := float32(cfg.Height) / 4
+	scaled := int(float32(samples) / (1 + (distance/radius)*(distance/radius)))
+	if scaled < 1 {
+		return 1
+	}
+	return scaled
+}
+
+// castRay traces a ray through the scene and returns the light it gathers, following it for at most
+// cfg.Bounces bounces. bounced counts all bounces so far and specularBounces the ones off specular materials.
+// throughput is the product of the attenuations along the path so far, which is
+// used to attribute the gathered light to layers[bounced] when layers is long enough.
+// The rays cast, and the intersection tests in CostMode, are counted in counts, if it is not nil.
+func (scene *Scene) castRay(cfg *RenderConfig, ray Ray, rng *rand.Rand, bounced int, specularBounces int, throughput Vec3, layers []Vec3, counts *pathCounts) Vec3 {
+	if bounced > cfg.Bounces || (cfg.MaxDiffuse >= 0 && bounced-specularBounces > cfg.MaxDiffuse) || (cfg.MaxSpecular >= 0 && specularBounces > cfg.MaxSpecular) {
+		return Vec3{0, 0, 0}
+	}
+	if counts != nil {
+		counts.rays++
+		if bounced > counts.maxDepth {
+			counts.maxDepth = bounced
+		}
+	}
+	var closestHit Hit
+	var didHit bool
+	if counts != nil && cfg.Mode == CostMode {
+		closestHit, didHit = intersectCounting(scene.world, ray, cfg.WorldScale, &counts.tests)
+	} else {
+		closestHit, didHit = scene.world.Intersect(ray, cfg.WorldScale)
+	}
+	if cfg.Mode == NormalsMode && didHit {
+		return MulScalar(0.5, Add(closestHit.Normal, Vec3{1, 1, 1}))
+	}
+	if didHit {
+		emission := emitted(closestHit.Material)
+		if bounced < len(layers) {
+			layers[bounced] = Add(layers[bounced], Mul(throughput, emission))
+		}
+		didScatter, attenuation, scatteredRay := closestHit.Material.Scatter(ray, closestHit, rng)
+		if didScatter && passedThrough(closestHit.Material, ray, scatteredRay) {
+			// Going through a faded surface is not a bounce, the ray just continues
+			return Add(emission, scene.castRay(cfg, scatteredRay, rng, bounced, specularBounces, throughput, layers, counts))
+		}
+		if didScatter {
+			if cfg.SpawnOffset > 0 {
+				scatteredRay.Origin = spawnOrigin(closestHit, scatteredRay.Direction, cfg.SpawnOffset)
+			}
+			if isSpecular(closestHit.Material, ray, closestHit, scatteredRay) {
+				specularBounces++
+			}
+			if cfg.Roulette > 0 && bounced+1 >= cfg.Roulette {
+				// Russian roulette: continue dim paths only sometimes, but make up for the terminated
+				// ones by dividing the light of the surviving ones by their chance to survive
+				survival := Min(Max(throughput.X*attenuation.X, Max(throughput.Y*attenuation.Y, throughput.Z*attenuation.Z)), 1)
+				if rng.Float32() >= survival {
+					return emission
+				}
+				attenuation = DivScalar(survival, attenuation)
+			}
+			color := Mul(attenuation, scene.castRay(cfg, scatteredRay, rng, bounced+1, specularBounces, Mul(throughput, attenuation), layers, counts))
+			if bounced == 1 && cfg.IndirectClamp > 0 {
+				// This is the light reaching the first hit by way of another surface: indirect light
+				color = ClampLuminance(color, cfg.IndirectClamp)
+			}
+			return Add(emission, color)
+		}
+		return emission
+	}
+
+	if bounced > 0 && !cfg.SkyLighting {
+		return Vec3{0, 0, 0}
+	}
+	color := scene.Background.Sample(ray.Direction)
+	if bounced == 0 {
+		color = MulScalar(cfg.BackgroundScale, color)
+	}
+	if bounced < len(layers) {
+		layers[bounced] = Add(layers[bounced], Mul(throughput, color))
+	}
+	return color
+}
+
+// spawnOrigin moves the start of a ray leaving a hit off the surface along the normal, to the side the
+// ray leaves on. Rounding errors in the hit position grow with its distance, so the offset does too:
+// scale times spawnEpsilon times the larger of the hit distance and the distance of the hit
+// position from the origin. This keeps the ray from hitting the same surface again (shadow acne)
+// in cases where the fixed epsilon of the shapes is too small.
+func spawnOrigin(hit Hit, direction Vec3, scale float32) Vec3 {
+	offset := scale * spawnEpsilon * Max(hit.T, hit.Position.Length())
+	if Dot(direction, hit.Normal) < 0 {
+		offset = -offset
+	}
+	return Add(hit.Position, MulScalar(offset, hit.Normal))
+}
+
+// Mode is what a render shows: the light in the scene, or debugging information
+type Mode int
+
+const (
+	// ColorMode path traces the scene
+	ColorMode Mode = iota
+	// NormalsMode shows the normal of the first hit, mapped from [-1, 1] to [0, 1], and the background
+	// where there is none
+	NormalsMode
+	// CostMode path traces the scene, but shows the average number of intersection tests of the paths
+	// through every pixel instead of their light. Framebuffer.Heatmap turns that into colors.
+	CostMode
+)
+
+// Modes maps the names of the modes to them
+var Modes = map[string]Mode{
+	"color":   ColorMode,
+	"normals": NormalsMode,
+	"cost":    CostMode,
+}
+
+// Filter is a pixel reconstruction filter: the weight of a sample at some offset from the pixel center.
+// Samples are drawn with the filter as their distribution, so they can be averaged without weights.
+type Filter int
+
+const (
+	// BoxFilter spreads samples uniformly over the pixel
+	BoxFilter Filter = iota
+	// TentFilter falls off linearly up to one pixel from the center
+	TentFilter
+	// GaussianFilter is a Gaussian with a standard deviation of half a pixel, cut off at 1.5 pixels
+	GaussianFilter
+)
+
+// Filters maps the names of the filters to them
+var Filters = map[string]Filter{
+	"box":      BoxFilter,
+	"tent":     TentFilter,
+	"gaussian": GaussianFilter,
+}
+
+// sampleFilter maps two uniform numbers in [0, 1) to the offset of a sample from the pixel center,
+// distributed like the filter. Mapping them instead of drawing the offset keeps the strata of
+// stratified sampling intact.
+func sampleFilter(kind Filter, u float32, v float32) (dx float32, dy float32) {
+	switch kind {
+	case TentFilter:
+		return sampleTent(u), sampleTent(v)
+	case GaussianFilter:
+		return sampleGaussian(u), sampleGaussian(v)
+	}
+	return u - 0.5, v - 0.5
+}
+
+// sampleTent maps a uniform number in [0, 1) to [-1, 1) with a triangular distribution, by inverting its CDF
+func sampleTent(u float32) float32 {
+	u *= 2
+	if u < 1 {
+		return Sqrt(u) - 1
+	}
+	return 1 - Sqrt(2-u)
+}
+
+// gaussianCutoff is the share of a Gaussian within three standard deviations of its center
+var gaussianCutoff = math.Erf(3 / math.Sqrt2)
+
+// sampleGaussian maps a uniform number in [0, 1) to a Gaussian with a standard deviation of half a
+// pixel by inverting its CDF. Only the part within 1.5 pixels is used, to keep samples from landing
+// several pixels away.
+func sampleGaussian(u float32) float32 {
+	return float32(0.5 * math.Sqrt2 * math.Erfinv((2*float64(u)-1)*gaussianCutoff))
+}
+
+// getColor averages the samples for a pixel and returns the color and the number of samples taken.
+// With a positive cfg.AdaptiveTolerance it stops early, once at least cfg.AdaptiveMinSamples have been taken
+// and the standard error of their mean luminance is below the tolerance. The per-bounce contributions
+// are averaged into layers
+func (scene *Scene) getColor(cfg *RenderConfig, x int, y int, samples int, rng *rand.Rand, layers []Vec3) (Vec3, int) {
+	for i := range layers {
+		layers[i] = Vec3{0, 0, 0}
+	}
+	color := Vec3{0, 0, 0}
+	var dx, dy float32
+	// Running mean and sum of squared differences of the sample luminances (Welford)
+	var mean, m2 float32
+	// With a square number of samples, every sample is jittered within its own cell of a grid over the pixel
+	grid := 0
+	if cfg.Stratified {
+		if n := int(math.Sqrt(float64(samples))); n*n == samples {
+			grid = n
+		}
+	}
+	taken := 0
+	var counts *pathCounts
+	if cfg.Mode == CostMode || cfg.Stats != nil {
+		counts = &pathCounts{}
+	}
+	for taken < samples {
+		if cfg.Antithetic && taken%2 == 1 {
+			// Mirror the offset of the previous sample around the pixel center
+			dx, dy = -dx, -dy
+		} else {
+			u, v := rng.Float32(), rng.Float32()
+			if grid > 0 {
+				u = (float32(taken%grid) + u) / float32(grid)
+				v = (float32(taken/grid) + v) / float32(grid)
+			}
+			dx, dy = sampleFilter(cfg.Filter, u, v)
+		}
+		ray := scene.Camera.getRay(float32(x)+dx, float32(y)+dy, rng)
+		sample := scene.castRay(cfg, ray, rng, 0, 0, Vec3{1, 1, 1}, layers, counts)
+		if cfg.SampleClamp > 0 {
+			sample = ClampLuminance(sample, cfg.SampleClamp)
+		}
+		color = Add(color, sample)
+		taken++
+
+		if cfg.AdaptiveTolerance <= 0 {
+			continue
+		}
+		luminance := Luminance(sample)
+		delta := luminance - mean
+		mean += delta / float32(taken)
+		m2 += delta * (luminance - mean)
+		// Antithetic pairs are only unbiased when complete
+		if taken >= cfg.AdaptiveMinSamples && taken > 1 && (!cfg.Antithetic || taken%2 == 0) && standardError(m2, taken) < cfg.AdaptiveTolerance {
+			break
+		}
+	}
+	for i := range layers {
+		layers[i] = DivScalar(float32(taken), layers[i])
+	}
+	if cfg.Stats != nil {
+		cfg.Stats.add(counts, taken)
+	}
+	if cfg.Mode == CostMode {
+		average := float32(counts.tests) / float32(taken)
+		return Vec3{average, average, average}, taken
+	}
+	return DivScalar(float32(taken), color), taken
+}
+
+// pixelSource is a small random number generator (SplitMix64) that is cheap to seed again for every
+// pixel. Seeding it from the pixel coordinates makes the samples of a pixel independent of the tile
+// it is rendered in, so tiles don't share noise patterns and the image is still reproducible.
+type pixelSource struct {
+	state uint64
+}
+
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Seed the generator
+func (src *pixelSource) Seed(seed int64) {
+	src.state = uint64(seed)
+}
+
+// seedPixel seeds the generator from a hash of the base seed, the pixel coordinates and the pass number.
+// mix64(0) is 0, so a base seed of 0 gives the same samples as before there was one.
+func (src *pixelSource) seedPixel(seed int64, x int, y int, pass int) {
+	src.state = mix64(mix64(mix64(uint64(x)^mix64(uint64(seed)))^uint64(y)) ^ uint64(pass))
+}
+
+// Uint64 returns the next random number
+func (src *pixelSource) Uint64() uint64 {
+	src.state += 0x9e3779b97f4a7c15
+	return mix64(src.state)
+}
+
+// Int63 returns the next random number without its sign bit
+func (src *pixelSource) Int63() int64 {
+	return int64(src.Uint64() >> 1)
+}
+
+// processTile renders pass number pass of a tile, averaging it into the passes already in the framebuffers
+func (scene *Scene) processTile(ctx context.Context, cfg *RenderConfig, fb *Framebuffer, layers []*Framebuffer, samples int, pass int, fromX int, fromY int, toX int, toY int) {
+	source := &pixelSource{}
+	rng := rand.New(source)
+	layerColors := make([]Vec3, len(layers))
+	for y := fromY; y < toY; y++ {
+		if ctx.Err() != nil {
+			return
+		}
+		for x := fromX; x < toX; x++ {
+			if !cfg.InMask(x, cfg.Height-y-1) {
+				continue
+			}
+			source.seedPixel(cfg.Seed, x, y, pass)
+			color, _ := scene.getColor(cfg, x, y, cfg.foveatedSamples(x, cfg.Height-y-1, samples), rng, layerColors)
+			fb.Accumulate(x, cfg.Height-y-1, color, pass)
+			for i, layer := range layers {
+				layer.Accumulate(x, cfg.Height-y-1, layerColors[i], pass)
+			}
+		}
+	}
+}
+
+// refineTile doubles the number of samples of every pixel in the tile whose standard error is still
+// above threshold, averaging the light gathered at each bounce into layers, and returns how many pixels
+// it refined. counts holds the samples taken per pixel.
+// Pixels marked in edges (if not nil) are refined until they have at least cfg.MinSamplesEdge samples,
+// all others until they have cfg.MinSamplesInterior, regardless of their standard error.
+func (scene *Scene) refineTile(ctx context.Context, cfg *RenderConfig, fb *Framebuffer, layers []*Framebuffer, counts []int, edges *Framebuffer, threshold float32, fromX int, fromY int, toX int, toY int) int {
+	source := &pixelSource{}
+	rng := rand.New(source)
+	layerColors := make([]Vec3, len(layers))
+	refined := 0
+	for y := fromY; y < toY; y++ {
+		if ctx.Err() != nil {
+			break
+		}
+		for x := fromX; x < toX; x++ {
+			if !cfg.InMask(x, cfg.Height-y-1) {
+				continue
+			}
+			i := (cfg.Height-y-1)*cfg.Width + x
+			floor := cfg.MinSamplesInterior
+			if edges != nil && edges.At(x, cfg.Height-y-1).X > 0 {
+				floor = cfg.MinSamplesEdge
+			}
+			if counts[i] >= floor && counts[i] > 1 && fb.PixelStandardError(x, cfg.Height-y-1, counts[i]) <= threshold {
+				continue
+			}
+			samples := counts[i]
+			if samples == 0 {
+				samples = 1
+			}
+			// Seeded by the number of samples so far, so every pass takes new samples
+			source.seedPixel(cfg.Seed, x, y, counts[i])
+			for s := 0; s < samples; s++ {
+				color, _ := scene.getColor(cfg, x, y, 1, rng, layerColors)
+				fb.Accumulate(x, cfg.Height-y-1, color, counts[i])
+				for j, layer := range layers {
+					layer.Accumulate(x, cfg.Height-y-1, layerColors[j], counts[i])
+				}
+				counts[i]++
+			}
+			refined++
+		}
+	}
+	return refined
+}
+
+// renderTiles splits the image into cfg.Tiles horizontal bands (or one per CPU if it is not positive)
+// and runs processTile on them with a worker per CPU
+func renderTiles(ctx context.Context, cfg *RenderConfig, processTile func(fromX int, fromY int, toX int, toY int)) {
+	tiles := cfg.Tiles
+	if tiles <= 0 {
+		tiles = runtime.NumCPU()
+	}
+	progress := newProgressReporter(cfg.Progress, tiles)
+	jobs := make(chan [4]int)
+	var waitGroup sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for tile := range jobs {
+				processTile(tile[0], tile[1], tile[2], tile[3])
+				progress.tileDone()
+			}
+		}()
+	}
+dispatch:
+	for i := 0; i < tiles; i++ {
+		// Rounding down both bounds makes the bands cover every row exactly once
+		select {
+		case jobs <- [4]int{0, i * cfg.Height / tiles, cfg.Width, (i + 1) * cfg.Height / tiles}:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	waitGroup.Wait()
+	progress.finish()
+}
+
+// progressInterval is the minimum time between two progress reports
+const progressInterval = 250 * time.Millisecond
+
+// progressReporter prints the share of the tiles that is done and the estimated time remaining.
+// The workers only touch it with atomic operations, so it doesn't make them wait for each other.
+type progressReporter struct {
+	w     io.Writer
+	tiles int64
+	start time.Time
+	done  int64
+	// lastReport is the time of the last report in nanoseconds since start
+	lastReport int64
+}
+
+func newProgressReporter(w io.Writer, tiles int) *progressReporter {
+	return &progressReporter{w: w, tiles: int64(tiles), start: time.Now(), lastReport: -int64(progressInterval)}
+}
+
+func (p *progressReporter) tileDone() {
+	if p.w == nil {
+		return
+	}
+	done := atomic.AddInt64(&p.done, 1)
+	elapsed := time.Since(p.start)
+	last := atomic.LoadInt64(&p.lastReport)
+	// Only the worker that wins the swap prints, so reports are at least progressInterval apart
+	if done < p.tiles && (int64(elapsed)-last < int64(progressInterval) || !atomic.CompareAndSwapInt64(&p.lastReport, last, int64(elapsed))) {
+		return
+	}
+	remaining := time.Duration(float64(elapsed) * float64(p.tiles-done) / float64(done))
+	fmt.Fprintf(p.w, "\r%3d%% done, %v remaining ", 100*done/p.tiles, remaining.Round(time.Second))
+}
+
+func (p *progressReporter) finish() {
+	if p.w != nil {
+		fmt.Fprintf(p.w, "\rdone in %v            \n", time.Since(p.start).Round(time.Millisecond))
+	}
+}
+
+// pathCounts counts the work done for the samples of a pixel
+type pathCounts struct {
+	// tests is the number of intersection tests, only counted in CostMode
+	tests    int
+	rays     int
+	maxDepth int
+}
+
+// RenderStats counts the rays cast while rendering. The workers only touch it with atomic operations,
+// once per pixel, so its fields should only be read after rendering.
+type RenderStats struct {
+	// Rays counts all rays cast, PrimaryRays the ones from the camera
+	Rays        int64
+	PrimaryRays int64
+	// MaxDepth is the largest number of bounces of a ray that was cast
+	MaxDepth int64
+}
+
+// add the counts of a pixel that took primary samples
+func (stats *RenderStats) add(counts *pathCounts, primary int) {
+	atomic.AddInt64(&stats.Rays, int64(counts.rays))
+	atomic.AddInt64(&stats.PrimaryRays, int64(primary))
+	for {
+		depth := atomic.LoadInt64(&stats.MaxDepth)
+		if int64(counts.maxDepth) <= depth || atomic.CompareAndSwapInt64(&stats.MaxDepth, depth, int64(counts.maxDepth)) {
+			return
+		}
+	}
+}
+
+// AverageBounces is the average number of bounces per primary ray
+func (stats *RenderStats) AverageBounces() float64 {
+	if stats.PrimaryRays == 0 {
+		return 0
+	}
+	return float64(stats.Rays-stats.PrimaryRays) / float64(stats.PrimaryRays)
+}
+
+// Render renders the scene with cfg.Samples samples per pixel. If ctx is cancelled it stops early,
+// and the rows it didn't get to are black.
+func Render(ctx context.Context, scene *Scene, cfg RenderConfig) *image.NRGBA {
+	fb := NewFramebuffer(cfg.Width, cfg.Height)
+	RenderPass(ctx, scene, &cfg, fb, nil, cfg.Samples, 0)
+	return fb.Image(cfg.Gamma)
+}
+
+// RenderPass renders pass number pass with the given number of samples per pixel, and averages it
+// into the earlier passes in fb. The light gathered at each bounce is averaged into layers. If ctx
+// is cancelled it stops early, leaving the rows it didn't get to as they were.
+func RenderPass(ctx context.Context, scene *Scene, cfg *RenderConfig, fb *Framebuffer, layers []*Framebuffer, samples int, pass int) {
+	renderTiles(ctx, cfg, func(fromX int, fromY int, toX int, toY int) {
+		scene.processTile(ctx, cfg, fb, layers, samples, pass, fromX, fromY, toX, toY)
+	})
+}
+
+// Refine doubles the number of samples of every pixel of fb whose standard error is still above
+// threshold, or that has fewer samples than cfg.MinSamplesEdge (for pixels marked in edges) or
+// cfg.MinSamplesInterior. The light gathered at each bounce is averaged into layers. counts holds the
+// number of samples of every pixel so far, and fb must track its variance. It returns the number of
+// pixels that were refined, and stops early if ctx is cancelled.
+func Refine(ctx context.Context, scene *Scene, cfg *RenderConfig, fb *Framebuffer, layers []*Framebuffer, counts []int, edges *Framebuffer, threshold float32) int {
+	var refined int64
+	renderTiles(ctx, cfg, func(fromX int, fromY int, toX int, toY int) {
+		atomic.AddInt64(&refined, int64(scene.refineTile(ctx, cfg, fb, layers, counts, edges, threshold, fromX, fromY, toX, toY)))
+	})
+	return int(refined)
+}
+
+// SamplePixel renders the pixel at (x, y) (counted from the top) with up to samples samples, like the
+// first pass of RenderPass does, and returns its color and the number of samples taken
+func SamplePixel(scene *Scene, cfg *RenderConfig, x int, y int, samples int) (Vec3, int) {
+	source := &pixelSource{}
+	source.seedPixel(cfg.Seed, x, cfg.Height-y-1, 0)
+	return scene.getColor(cfg, x, cfg.Height-y-1, samples, rand.New(source), nil)
+}
@@ -0,0 +1,316 @@
+package raytracer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testConfig is a small, quick render
+func testConfig() RenderConfig {
+	cfg := DefaultRenderConfig()
+	cfg.Width, cfg.Height, cfg.Samples = 32, 18, 4
+	return cfg
+}
+
+// testScene is the dielectrics scene seen by the default camera
+func testScene(cfg *RenderConfig) *Scene {
+	camera := NewCamera(Vec3{0, 0, 0}, Vec3{0, 0, 1}, Vec3{0, 1, 0}, DefaultFieldOfView, 0, 0, cfg)
+	return NewScene(dielectricsScene(), camera, DefaultBackground)
+}
+
+func TestRenderIsReproducible(t *testing.T) {
+	cfg := testConfig()
+	scene := testScene(&cfg)
+	first := Render(context.Background(), scene, cfg)
+	// The samples of a pixel don't depend on the tile it is in
+	cfg.Tiles = 5
+	second := Render(context.Background(), scene, cfg)
+	if !bytes.Equal(first.Pix, second.Pix) {
+		t.Error("rendering the same scene twice gave different images")
+	}
+}
+
+func TestSeedChangesOnlyTheNoise(t *testing.T) {
+	cfg := testConfig()
+	cfg.Seed = 7
+	scene := testScene(&cfg)
+	first, again := Render(context.Background(), scene, cfg), Render(context.Background(), scene, cfg)
+	if !bytes.Equal(first.Pix, again.Pix) {
+		t.Error("rendering twice with seed 7 gave different images")
+	}
+	cfg.Seed = 8
+	other := Render(context.Background(), scene, cfg)
+	if bytes.Equal(first.Pix, other.Pix) {
+		t.Error("seeds 7 and 8 gave the same image")
+	}
+}
+
+// meanLuminance of a framebuffer
+func meanLuminance(fb *Framebuffer) float32 {
+	var sum float32
+	for _, pixel := range fb.Pixels {
+		sum += Luminance(pixel)
+	}
+	return sum / float32(len(fb.Pixels))
+}
+
+// renderCounting renders the scene into a framebuffer and counts the rays cast
+func renderCounting(scene *Scene, cfg RenderConfig) (*Framebuffer, *RenderStats) {
+	cfg.Stats = &RenderStats{}
+	fb := NewFramebuffer(cfg.Width, cfg.Height)
+	RenderPass(context.Background(), scene, &cfg, fb, nil, cfg.Samples, 0)
+	return fb, cfg.Stats
+}
+
+func TestRouletteCastsFewerRays(t *testing.T) {
+	cfg := testConfig()
+	cfg.Samples = 16
+	scene := testScene(&cfg)
+	full, fullStats := renderCounting(scene, cfg)
+	cfg.Roulette = 3
+	roulette, rouletteStats := renderCounting(scene, cfg)
+	if rouletteStats.Rays >= fullStats.Rays {
+		t.Errorf("with roulette %d rays were cast, without it %d", rouletteStats.Rays, fullStats.Rays)
+	}
+	// Surviving paths make up for the terminated ones, so the image is as bright on average
+	if got, want := meanLuminance(roulette), meanLuminance(full); !closeTo(got, want, 0.02*want) {
+		t.Errorf("the mean luminance with roulette is %v, without it %v", got, want)
+	}
+}
+
+func BenchmarkRoulette(b *testing.B) {
+	for _, from := range []int{0, 3} {
+		b.Run(fmt.Sprintf("from-%d", from), func(b *testing.B) {
+			cfg := testConfig()
+			cfg.Roulette = from
+			scene := testScene(&cfg)
+			var rays int64
+			for i := 0; i < b.N; i++ {
+				_, stats := renderCounting(scene, cfg)
+				rays += stats.Rays
+			}
+			b.ReportMetric(float64(rays)/float64(b.N), "rays/op")
+		})
+	}
+}
+
+func TestAdaptiveSamplingStopsEarlyOnUniformPixels(t *testing.T) {
+	cfg := testConfig()
+	cfg.Samples, cfg.AdaptiveTolerance, cfg.AdaptiveMinSamples = 256, 0.01, 16
+	camera := NewCamera(Vec3{0, 0, 0}, Vec3{0, 0, 1}, Vec3{0, 1, 0}, DefaultFieldOfView, 0, 0, &cfg)
+	sphere := Sphere{Vec3{0, 0, 3}, 1, Emissive{Vec3{0, 0, 0}}}
+	scene := NewScene([]Shape{sphere}, camera, SolidBackground{Vec3{1, 1, 1}})
+
+	if _, taken := SamplePixel(scene, &cfg, 0, 0, cfg.Samples); taken != cfg.AdaptiveMinSamples {
+		t.Errorf("the uniform background took %d samples, want the minimum of %d", taken, cfg.AdaptiveMinSamples)
+	}
+	// Across the middle row, the pixels on the outline of the sphere are a mix of black and white
+	most := 0
+	for x := 0; x < cfg.Width; x++ {
+		if _, taken := SamplePixel(scene, &cfg, x, cfg.Height/2, cfg.Samples); taken > most {
+			most = taken
+		}
+	}
+	if most <= 2*cfg.AdaptiveMinSamples {
+		t.Errorf("the pixels on the edge of the sphere took at most %d samples, want more than %d", most, 2*cfg.AdaptiveMinSamples)
+	}
+}
+
+// filterMoments are the mean and variance of the horizontal offsets of n samples of the filter
+func filterMoments(kind Filter, n int) (mean float64, variance float64) {
+	rng := rand.New(rand.NewSource(1))
+	var sum, sumSquares float64
+	for i := 0; i < n; i++ {
+		dx, _ := sampleFilter(kind, rng.Float32(), rng.Float32())
+		sum += float64(dx)
+		sumSquares += float64(dx) * float64(dx)
+	}
+	mean = sum / float64(n)
+	return mean, sumSquares/float64(n) - mean*mean
+}
+
+func TestFilterVariance(t *testing.T) {
+	tests := []struct {
+		kind         Filter
+		wantVariance float64
+	}{
+		{BoxFilter, 1.0 / 12},
+		{TentFilter, 1.0 / 6},
+		// Slightly below 0.25, because the tails beyond 1.5 pixels are cut off
+		{GaussianFilter, 0.243},
+	}
+	for _, test := range tests {
+		mean, variance := filterMoments(test.kind, 100000)
+		if math.Abs(mean) > 0.01 || math.Abs(variance-test.wantVariance) > 0.01*test.wantVariance+0.001 {
+			t.Errorf("filter %d has mean %v and variance %v, want 0 and %v", test.kind, mean, variance, test.wantVariance)
+		}
+	}
+}
+
+func TestBoxFilterKeepsTheUniformOffsets(t *testing.T) {
+	for _, u := range []float32{0, 0.25, 0.5, 0.999} {
+		if dx, dy := sampleFilter(BoxFilter, u, 1-u); dx != u-0.5 || dy != (1-u)-0.5 {
+			t.Errorf("the box filter maps (%v, %v) to (%v, %v), want (%v, %v)", u, 1-u, dx, dy, u-0.5, (1-u)-0.5)
+		}
+	}
+	for _, u := range []float32{0, 0.5, 0.999} {
+		if dx, _ := sampleFilter(TentFilter, u, 0); dx < -1 || dx >= 1 {
+			t.Errorf("the tent filter maps %v to %v, outside [-1, 1)", u, dx)
+		}
+		if dx, _ := sampleFilter(GaussianFilter, u, 0); dx < -1.5 || dx > 1.5 {
+			t.Errorf("the Gaussian filter maps %v to %v, outside [-1.5, 1.5]", u, dx)
+		}
+	}
+}
+
+func TestNormalsModeShowsTheNormal(t *testing.T) {
+	cfg := testConfig()
+	cfg.Mode = NormalsMode
+	camera := NewCamera(Vec3{0, 0, 0}, Vec3{0, 0, 1}, Vec3{0, 1, 0}, DefaultFieldOfView, 0, 0, &cfg)
+	scene := NewScene([]Shape{Sphere{Vec3{0, 0, 3}, 1, Lambertian{SolidColor{Vec3{1, 1, 1}}}}}, camera, SolidBackground{Vec3{0.2, 0.3, 0.4}})
+	rng := rand.New(rand.NewSource(1))
+	// The front of the sphere faces the camera, with normal (0, 0, -1)
+	if got := scene.Trace(Ray{Vec3{0, 0, 0}, Vec3{0, 0, 1}, 0}, rng, &cfg); !vecCloseTo(got, Vec3{0.5, 0.5, 0}, 1e-5) {
+		t.Errorf("the head-on hit shows %v, want (0.5, 0.5, 0)", got)
+	}
+	if got := scene.Trace(Ray{Vec3{0, 0, 0}, Vec3{0, 1, 0}, 0}, rng, &cfg); !vecCloseTo(got, Vec3{0.2, 0.3, 0.4}, 1e-5) {
+		t.Errorf("the miss shows %v, want the background (0.2, 0.3, 0.4)", got)
+	}
+}
+
+func TestCostModeCountsIntersectionTests(t *testing.T) {
+	cfg := testConfig()
+	cfg.Mode = CostMode
+	camera := NewCamera(Vec3{0, 0, 0}, Vec3{0, 0, 1}, Vec3{0, 1, 0}, DefaultFieldOfView, 0, 0, &cfg)
+	gray := Lambertian{SolidColor{Vec3{0.5, 0.5, 0.5}}}
+	scene := NewScene([]Shape{Sphere{Vec3{-1.2, 0, 4}, 1, gray}, Sphere{Vec3{1.2, 0, 4}, 1, gray}}, camera, DefaultBackground)
+	// The rays through the center pass between the spheres, inside the box around both
+	between, _ := SamplePixel(scene, &cfg, cfg.Width/2, cfg.Height/2, cfg.Samples)
+	corner, _ := SamplePixel(scene, &cfg, 0, 0, cfg.Samples)
+	if between.X <= corner.X {
+		t.Errorf("a pixel between the spheres took %v intersection tests, a corner %v, want more", between.X, corner.X)
+	}
+}
+
+// pixelVariance is the variance of the luminance of the pixel over renders with different seeds
+func pixelVariance(scene *Scene, cfg RenderConfig, x int, y int, renders int) float64 {
+	var sum, sumSquares float64
+	for seed := 0; seed < renders; seed++ {
+		cfg.Seed = int64(seed)
+		color, _ := SamplePixel(scene, &cfg, x, y, cfg.Samples)
+		luminance := float64(Luminance(color))
+		sum += luminance
+		sumSquares += luminance * luminance
+	}
+	mean := sum / float64(renders)
+	return sumSquares/float64(renders) - mean*mean
+}
+
+func TestStratifiedSamplingReducesVariance(t *testing.T) {
+	cfg := testConfig()
+	cfg.Samples = 64
+	camera := NewCamera(Vec3{0, 0, 0}, Vec3{0, 0, 1}, Vec3{0, 1, 0}, DefaultFieldOfView, 0, 0, &cfg)
+	scene := NewScene([]Shape{Sphere{Vec3{0, 0, 3}, 1, Emissive{Vec3{0, 0, 0}}}}, camera, SolidBackground{Vec3{1, 1, 1}})
+	// The pixel of the middle row closest to half covered by the black sphere
+	edge, closest := 0, float32(1)
+	for x := 0; x < cfg.Width; x++ {
+		color, _ := SamplePixel(scene, &cfg, x, cfg.Height/2, cfg.Samples)
+		if distance := Abs(Luminance(color) - 0.5); distance < closest {
+			edge, closest = x, distance
+		}
+	}
+	random := pixelVariance(scene, cfg, edge, cfg.Height/2, 200)
+	cfg.Stratified = true
+	stratified := pixelVariance(scene, cfg, edge, cfg.Height/2, 200)
+	if stratified >= random/2 {
+		t.Errorf("the variance of the edge pixel %d is %v with stratified sampling and %v without, want less than half", edge, stratified, random)
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	cfg := testConfig()
+	scene := testScene(&cfg)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Render(context.Background(), scene, cfg)
+	}
+}
+
+func TestRenderStopsWhenCancelled(t *testing.T) {
+	cfg := testConfig()
+	cfg.Tiles = 4
+	scene := testScene(&cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	img := Render(ctx, scene, cfg)
+	black := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i] == 0 && img.Pix[i+1] == 0 && img.Pix[i+2] == 0 {
+			black++
+		}
+	}
+	if pixels := cfg.Width * cfg.Height; black < pixels*9/10 {
+		t.Errorf("%d of the %d pixels of a cancelled render are black, want nearly all", black, pixels)
+	}
+}
+
+func TestRenderStatsCountTheRays(t *testing.T) {
+	cfg := testConfig()
+	cfg.Tiles = 4
+	camera := NewCamera(Vec3{0, 0, 0}, Vec3{0, 0, 1}, Vec3{0, 1, 0}, DefaultFieldOfView, 0, 0, &cfg)
+	sphere := Sphere{Vec3{0, 0, 3}, 1, Lambertian{SolidColor{Vec3{0.5, 0.5, 0.5}}}}
+	scene := NewScene([]Shape{sphere}, camera, DefaultBackground)
+	_, stats := renderCounting(scene, cfg)
+	if want := int64(cfg.Width * cfg.Height * cfg.Samples); stats.PrimaryRays != want {
+		t.Errorf("%d primary rays were counted, want one per sample, %d", stats.PrimaryRays, want)
+	}
+	if stats.Rays <= stats.PrimaryRays {
+		t.Errorf("%d rays were cast for %d primary rays, want some bounces", stats.Rays, stats.PrimaryRays)
+	}
+	// Rays bounce off a lone convex sphere at most once, and only the camera rays that hit it do
+	if average := stats.AverageBounces(); average <= 0 || average >= 1 {
+		t.Errorf("the rays bounced %v times on average, want between 0 and 1", average)
+	}
+	if stats.MaxDepth != 1 {
+		t.Errorf("the deepest ray bounced %d times, want 1", stats.MaxDepth)
+	}
+}
+
+// scaledScene is a sphere on a floor lit by a quad overhead, ten meters from the origin, in a scene
+// with scale units per meter
+func scaledScene(cfg *RenderConfig, scale float32) *Scene {
+	gray := Lambertian{SolidColor{Vec3{0.7, 0.7, 0.7}}}
+	at := func(x float32, y float32, z float32) Vec3 { return MulScalar(scale, Vec3{x + 10, y, z + 10}) }
+	shapes := []Shape{
+		Sphere{at(0, 0, 3), scale, gray},
+		Plane{Vec3{0, 1, 0}, -scale, gray},
+		Quad{at(-1, 2, 2), Vec3{2 * scale, 0, 0}, Vec3{0, 0, 2 * scale}, Emissive{Vec3{4, 4, 4}}},
+	}
+	camera := NewCamera(at(0, 0.5, -1), at(0, 0, 3), Vec3{0, 1, 0}, DefaultFieldOfView, 0, 0, cfg)
+	return NewScene(shapes, camera, SolidBackground{Vec3{0, 0, 0}})
+}
+
+func TestWorldScaleKeepsTheImage(t *testing.T) {
+	cfg := testConfig()
+	cfg.Samples = 16
+	meters := NewFramebuffer(cfg.Width, cfg.Height)
+	RenderPass(context.Background(), scaledScene(&cfg, 1), &cfg, meters, nil, cfg.Samples, 0)
+	// Millimeters and kilometers
+	for _, scale := range []float32{1000, 0.001} {
+		cfg.WorldScale = scale
+		scaled := NewFramebuffer(cfg.Width, cfg.Height)
+		RenderPass(context.Background(), scaledScene(&cfg, scale), &cfg, scaled, nil, cfg.Samples, 0)
+		var largest float32
+		for i := range meters.Pixels {
+			largest = Max(largest, Abs(Luminance(scaled.Pixels[i])-Luminance(meters.Pixels[i])))
+		}
+		if largest > 0.02 {
+			t.Errorf("with %v units per meter a pixel differs by %v from the render in meters", scale, largest)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package raytracer
+
+import "math/rand"
+
+// Scene is everything that is rendered: the shapes, the camera looking at them and the background
+// behind them. Create it with NewScene, which builds the bounding volume hierarchy of the shapes.
+type Scene struct {
+	Shapes     []Shape
+	Camera     Camera
+	Background Background
+	world      Shape
+}
+
+// NewScene creates a scene of the shapes, seen by camera in front of background
+func NewScene(shapes []Shape, camera Camera, background Background) *Scene {
+	return &Scene{shapes, camera, background, NewBVH(shapes)}
+}
+
+// Trace returns the light that a ray gathers in the scene, following it for at most cfg.Bounces bounces
+func (scene *Scene) Trace(ray Ray, rng *rand.Rand, cfg *RenderConfig) Vec3 {
+	return scene.castRay(cfg, ray, rng, 0, 0, Vec3{1, 1, 1}, nil, nil)
+}
+
+// BVHSize returns the number of nodes of the bounding volume hierarchy of the scene and its depth,
+// the largest number of nodes on the way from the root to a shape
+func (scene *Scene) BVHSize() (nodes int, depth int) {
+	return bvhSize(scene.world)
+}
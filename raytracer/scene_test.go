@@ -0,0 +1,20 @@
+package raytracer
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSceneTrace(t *testing.T) {
+	cfg := testConfig()
+	camera := NewCamera(Vec3{0, 0, 0}, Vec3{0, 0, 1}, Vec3{0, 1, 0}, DefaultFieldOfView, 0, 0, &cfg)
+	light := Sphere{Vec3{0, 0, 3}, 1, Emissive{Vec3{4, 2, 1}}}
+	scene := NewScene([]Shape{light}, camera, SolidBackground{Vec3{0.1, 0.2, 0.3}})
+	rng := rand.New(rand.NewSource(1))
+	if got := scene.Trace(Ray{Vec3{0, 0, 0}, Vec3{0, 0, 1}, 0}, rng, &cfg); got != (Vec3{4, 2, 1}) {
+		t.Errorf("the ray into the light gathered %v, want its color (4, 2, 1)", got)
+	}
+	if got := scene.Trace(Ray{Vec3{0, 0, 0}, Vec3{0, 0, -1}, 0}, rng, &cfg); got != (Vec3{0.1, 0.2, 0.3}) {
+		t.Errorf("the ray away from the light gathered %v, want the background (0.1, 0.2, 0.3)", got)
+	}
+}
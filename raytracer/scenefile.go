@@ -0,0 +1,162 @@
+package raytracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// sceneFile is the JSON document read by LoadScene:
+//
+//	{
+//		"camera": {"position": [0, 0, 0], "target": [0, 0, 1], "up": [0, 1, 0], "fov": 90,
+//		           "aperture": 0.1, "focusDist": 2},
+//		"shapes": [
+//			{"type": "sphere", "position": [0, 0, 2], "radius": 0.5,
+//			 "material": {"type": "lambertian", "albedo": [0.1, 0.2, 0.5]}},
+//			{"type": "plane", "normal": [0, 1, 0], "along": -1,
+//			 "material": {"type": "metal", "albedo": [0.8, 0.8, 0.8], "fuzz": 0.1}}
+//		]
+//	}
+//
+// The camera is optional and defaults to the camera of the built-in scenes, as do its up and fov.
+// Without an aperture it is a pinhole camera, and it focuses on the target without a focusDist.
+// With a "viewHeight" instead of a fov it is an orthographic camera seeing that much of the scene vertically.
+type sceneFile struct {
+	Camera *cameraJSON
+	Shapes []json.RawMessage
+}
+
+type cameraJSON struct {
+	Position  vec3JSON
+	Target    vec3JSON
+	Up        vec3JSON
+	FOV       float32
+	Aperture  float32
+	FocusDist float32
+	// ViewHeight selects an orthographic camera, if it is positive
+	ViewHeight float32
+}
+
+// vec3JSON is a Vec3 written as [x, y, z]
+type vec3JSON [3]float32
+
+func (v vec3JSON) Vec3() Vec3 {
+	return Vec3{v[0], v[1], v[2]}
+}
+
+// shapeJSON decodes any kind of shape, picking the type from its "type" field
+type shapeJSON struct {
+	Shape Shape
+}
+
+// UnmarshalJSON decodes a shape
+func (s *shapeJSON) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Type     string
+		Position vec3JSON
+		Radius   float32
+		Normal   vec3JSON
+		Along    float32
+		Min      vec3JSON
+		Max      vec3JSON
+		Axis     vec3JSON
+		Height   float32
+		Capped   bool
+		U        vec3JSON
+		V        vec3JSON
+		Material *materialJSON
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if fields.Material == nil {
+		return fmt.Errorf("%s has no material", fields.Type)
+	}
+	switch fields.Type {
+	case "sphere":
+		s.Shape = Sphere{fields.Position.Vec3(), fields.Radius, fields.Material.Material}
+	case "plane":
+		s.Shape = Plane{Normalize(fields.Normal.Vec3()), fields.Along, fields.Material.Material}
+	case "cylinder":
+		s.Shape = Cylinder{fields.Position.Vec3(), Normalize(fields.Axis.Vec3()), fields.Radius, fields.Height, fields.Capped, fields.Material.Material}
+	case "quad":
+		s.Shape = Quad{fields.Position.Vec3(), fields.U.Vec3(), fields.V.Vec3(), fields.Material.Material}
+	case "box":
+		s.Shape = Box{minVec3(fields.Min.Vec3(), fields.Max.Vec3()), maxVec3(fields.Min.Vec3(), fields.Max.Vec3()), fields.Material.Material}
+	default:
+		return fmt.Errorf("unknown shape type %q", fields.Type)
+	}
+	return nil
+}
+
+// materialJSON decodes any kind of material, picking the type from its "type" field
+type materialJSON struct {
+	Material Material
+}
+
+// UnmarshalJSON decodes a material
+func (m *materialJSON) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Type   string
+		Albedo vec3JSON
+		Fuzz   float32
+		Index  float32
+		Color  vec3JSON
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	switch fields.Type {
+	case "lambertian":
+		m.Material = Lambertian{SolidColor{fields.Albedo.Vec3()}}
+	case "metal":
+		m.Material = Metal{fields.Albedo.Vec3(), fields.Fuzz}
+	case "dielectric":
+		m.Material = Dielectric{fields.Index}
+	case "emissive":
+		m.Material = Emissive{fields.Color.Vec3()}
+	default:
+		return fmt.Errorf("unknown material type %q", fields.Type)
+	}
+	return nil
+}
+
+// LoadScene reads the shapes and camera of a scene from a JSON file, setting the camera up for the image size in cfg
+func LoadScene(path string, cfg *RenderConfig) ([]Shape, Camera, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, Camera{}, err
+	}
+	var file sceneFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, Camera{}, fmt.Errorf("%s: %v", path, err)
+	}
+
+	shapes := make([]Shape, len(file.Shapes))
+	for i, raw := range file.Shapes {
+		var shape shapeJSON
+		if err := json.Unmarshal(raw, &shape); err != nil {
+			return nil, Camera{}, fmt.Errorf("%s: shape %d: %v", path, i, err)
+		}
+		shapes[i] = shape.Shape
+	}
+
+	camera := NewCamera(Vec3{0, 0, 0}, Vec3{0, 0, 1}, Vec3{0, 1, 0}, DefaultFieldOfView, 0, 0, cfg)
+	if file.Camera != nil {
+		fov := file.Camera.FOV
+		if fov == 0 {
+			fov = DefaultFieldOfView
+		}
+		up := file.Camera.Up.Vec3()
+		if up == (Vec3{}) {
+			up = Vec3{0, 1, 0}
+		}
+		if file.Camera.ViewHeight > 0 {
+			camera = NewOrthographicCamera(file.Camera.Position.Vec3(), file.Camera.Target.Vec3(), up, file.Camera.ViewHeight, cfg)
+		} else {
+			camera = NewCamera(file.Camera.Position.Vec3(), file.Camera.Target.Vec3(), up, fov, file.Camera.Aperture, file.Camera.FocusDist, cfg)
+		}
+	}
+	return shapes, camera, nil
+}
@@ -0,0 +1,207 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// Scenes maps the names of the built-in scenes to the functions building them
+var Scenes = map[string]func() []Shape{
+	"dielectrics":  dielectricsScene,
+	"metal-balls":  metalBallsScene,
+	"custom":       customScene,
+	"sphere-field": sphereFieldScene,
+	"far-away":     farAwayScene,
+	"glow":         glowScene,
+	"motion":       motionScene,
+	"fog":          fogScene,
+	"blobs":        blobsScene,
+}
+
+// BuildParallel generates n shapes by calling gen for every index, spreading the work over all CPUs.
+// gen is called concurrently, so it must not share state (like a *rand.Rand) between indices.
+func BuildParallel(gen func(i int) Shape, n int) []Shape {
+	shapes := make([]Shape, n)
+	workers := runtime.NumCPU()
+	chunk := (n + workers - 1) / workers
+	var waitGroup sync.WaitGroup
+	for from := 0; from < n; from += chunk {
+		to := from + chunk
+		if to > n {
+			to = n
+		}
+		waitGroup.Add(1)
+		go func(from int, to int) {
+			defer waitGroup.Done()
+			for i := from; i < to; i++ {
+				shapes[i] = gen(i)
+			}
+		}(from, to)
+	}
+	waitGroup.Wait()
+	return shapes
+}
+
+// My own scene
+func customScene() []Shape {
+	return []Shape{
+		Sphere{Vec3{1, 1, 3}, 0.5, Metal{Vec3{1, 1, 1}, 0.3}},
+		Plane{Vec3{0, 1, 0}, -1, Lambertian{SolidColor{Vec3{0.7, 0.8, 1.0}}}},
+		Sphere{Vec3{0, -0.5, 2}, 0.5, Lambertian{SolidColor{Vec3{0, 1, 0}}}},
+		Sphere{Vec3{-3, 2, 2}, 0.5, Lambertian{SolidColor{Vec3{1, 1, 0}}}},
+		Sphere{Vec3{0, 1, 2}, 0.5, Lambertian{SolidColor{Vec3{1, 0, 1}}}},
+	}
+}
+
+// Two metal balls
+func metalBallsScene() []Shape {
+	return []Shape{
+		Plane{Vec3{0, 1, 0}, -1, Lambertian{SolidColor{Vec3{140 / 255., 245 / 255., 98 / 255.}}}},
+		Sphere{Vec3{-2, 0, 2}, 1, Metal{Vec3{1, 1, 1}, 0.2}},
+		Sphere{Vec3{0, 0, 2}, 1, Lambertian{SolidColor{Vec3{255 / 255., 200 / 255., 210 / 255.}}}},
+		Sphere{Vec3{2, 0, 2}, 1, Metal{Vec3{0.8, 0.75, 1}, 0}},
+	}
+}
+
+// Dielectrics
+func dielectricsScene() []Shape {
+	return []Shape{
+		Sphere{Vec3{0, 0, 2}, 0.5, Lambertian{SolidColor{Vec3{0.1, 0.2, 0.5}}}},
+		Sphere{Vec3{0, -100.5, 1}, 100, Lambertian{SolidColor{Vec3{0.8, 0.8, 0.0}}}},
+		Sphere{Vec3{1, 0, 2}, 0.5, Metal{Vec3{0.8, 0.6, 0.2}, 0}},
+		Sphere{Vec3{-1, 0, 2}, 0.45, Dielectric{1.5}},
+	}
+}
+
+// Field of small random spheres, like the cover of Ray Tracing in a Weekend
+func sphereFieldScene() []Shape {
+	const gridSize = 22
+	field := BuildParallel(func(i int) Shape {
+		rng := rand.New(rand.NewSource(int64(i)))
+		center := Vec3{float32(i%gridSize-gridSize/2) + 0.9*rng.Float32(), -0.3, float32(i/gridSize) + 0.9*rng.Float32()}
+		switch choice := rng.Float32(); {
+		case choice < 0.8:
+			albedo := Mul(Vec3{rng.Float32(), rng.Float32(), rng.Float32()}, Vec3{rng.Float32(), rng.Float32(), rng.Float32()})
+			return Sphere{center, 0.2, Lambertian{SolidColor{albedo}}}
+		case choice < 0.95:
+			albedo := AddScalar(0.5, MulScalar(0.5, Vec3{rng.Float32(), rng.Float32(), rng.Float32()}))
+			return Sphere{center, 0.2, Metal{albedo, 0.5 * rng.Float32()}}
+		default:
+			return Sphere{center, 0.2, Dielectric{1.5}}
+		}
+	}, gridSize*gridSize)
+	return append(field,
+		Sphere{Vec3{0, -1000.5, 0}, 1000, Lambertian{SolidColor{Vec3{0.5, 0.5, 0.5}}}},
+		Sphere{Vec3{-1.5, 0.5, 4}, 1, Dielectric{1.5}},
+		Sphere{Vec3{1.5, 0.5, 6}, 1, Metal{Vec3{0.7, 0.6, 0.5}, 0}},
+	)
+}
+
+// Large spheres far from the origin, where float32 rounding errors in the hit positions are bigger
+// than the fixed intersection epsilon. Without -spawn-offset they are covered in shadow acne.
+func farAwayScene() []Shape {
+	return []Shape{
+		Sphere{Vec3{0, -512500, 500000}, 500000, Lambertian{SolidColor{Vec3{0.8, 0.8, 0.0}}}},
+		Sphere{Vec3{0, 0, 100000}, 25000, Lambertian{SolidColor{Vec3{0.1, 0.2, 0.5}}}},
+		Sphere{Vec3{55000, 0, 100000}, 25000, Metal{Vec3{0.8, 0.6, 0.2}, 0}},
+	}
+}
+
+// A dark room lit only by a glowing sphere. It is enclosed in a black sphere, which hides the background.
+func glowScene() []Shape {
+	return []Shape{
+		Sphere{Vec3{0, 0, 0}, 100, Emissive{Vec3{0, 0, 0}}},
+		Plane{Vec3{0, 1, 0}, -0.5, Lambertian{SolidColor{Vec3{0.8, 0.8, 0.8}}}},
+		Sphere{Vec3{0, 0.25, 2.5}, 0.3, Emissive{Vec3{4, 3.5, 3}}},
+		Sphere{Vec3{-1, -0.1, 2.5}, 0.4, Lambertian{SolidColor{Vec3{0.1, 0.2, 0.5}}}},
+		Sphere{Vec3{1, -0.1, 2.5}, 0.4, Metal{Vec3{0.8, 0.6, 0.2}, 0}},
+	}
+}
+
+// A ball rolling to the right and one falling down, to render with -motion-blur
+func motionScene() []Shape {
+	return []Shape{
+		Sphere{Vec3{0, -100.5, 1}, 100, Lambertian{SolidColor{Vec3{0.8, 0.8, 0.0}}}},
+		MovingSphere{Vec3{-0.8, 0, 2}, Vec3{-0.4, 0, 2}, 0.5, Lambertian{SolidColor{Vec3{0.1, 0.2, 0.5}}}},
+		MovingSphere{Vec3{1, 0.3, 2}, Vec3{1, 0, 2}, 0.5, Metal{Vec3{0.8, 0.6, 0.2}, 0}},
+	}
+}
+
+// A ball of white smoke and a box of thick blue fog, with a solid ball behind them
+func fogScene() []Shape {
+	return []Shape{
+		Sphere{Vec3{0, -100.5, 1}, 100, Lambertian{SolidColor{Vec3{0.8, 0.8, 0.0}}}},
+		ConstantMedium{Sphere{Vec3{-0.6, 0, 2}, 0.5, nil}, 2, Isotropic{SolidColor{Vec3{0.9, 0.9, 0.9}}}},
+		ConstantMedium{Box{Vec3{0.2, -0.5, 1.8}, Vec3{1, 0.3, 2.4}, nil}, 8, Isotropic{SolidColor{Vec3{0.2, 0.4, 0.9}}}},
+		Sphere{Vec3{0, 0, 3.5}, 0.5, Metal{Vec3{0.8, 0.6, 0.2}, 0}},
+	}
+}
+
+// Two balls melted together, a ray marched signed distance function, next to a plain sphere
+func blobsScene() []Shape {
+	blob := SmoothUnion(SphereSDF(Vec3{-0.9, 0, 2.5}, 0.5), SphereSDF(Vec3{-0.2, 0.1, 2.5}, 0.4), 0.3)
+	return []Shape{
+		Sphere{Vec3{0, -100.5, 1}, 100, Lambertian{SolidColor{Vec3{0.8, 0.8, 0.0}}}},
+		SDFShape{blob, Lambertian{SolidColor{Vec3{0.1, 0.2, 0.5}}}},
+		Sphere{Vec3{0.9, 0, 2.5}, 0.5, Metal{Vec3{0.8, 0.6, 0.2}, 0}},
+	}
+}
+
+// materialLibrary holds the materials shown by -material-preview
+var materialLibrary = []struct {
+	Name     string
+	Material Material
+}{
+	{"lambertian", Lambertian{SolidColor{Vec3{0.8, 0.3, 0.3}}}},
+	{"checkerboard", Lambertian{Checkerboard{SolidColor{Vec3{0.8, 0.3, 0.3}}, SolidColor{Vec3{0.9, 0.9, 0.9}}, 20}}},
+	{"uniform-lambertian", UniformLambertian{Vec3{0.8, 0.3, 0.3}}},
+	{"metal", Metal{Vec3{0.8, 0.8, 0.8}, 0}},
+	{"brushed-metal", Metal{Vec3{0.8, 0.8, 0.8}, 0.3}},
+	{"gold", Gold},
+	{"copper", Copper},
+	{"aluminum", Aluminum},
+	{"glass", Dielectric{1.5}},
+	{"depth-fade", DepthFade{Lambertian{SolidColor{Vec3{0.3, 0.8, 0.3}}}, 3, 6}},
+}
+
+// Label is a text to show next to a point in the scene
+type Label struct {
+	Text     string
+	Position Vec3
+}
+
+// StudioBackground is the dark backdrop of MaterialPreviewScene, which brings its own lights
+var StudioBackground Background = SolidBackground{Vec3{0.05, 0.05, 0.05}}
+
+// MaterialPreviewScene puts a sphere of every material in the library on a grid in front of the
+// default camera, above a gray floor. The spheres are lit like in a photo studio, by a large soft
+// light above the camera and a dimmer one on the right, both out of view, in front of StudioBackground.
+// It returns the name of every material at the point just below its sphere as labels.
+func MaterialPreviewScene() ([]Shape, []Label) {
+	columns := int(math.Ceil(math.Sqrt(float64(len(materialLibrary)))))
+	rows := (len(materialLibrary) + columns - 1) / columns
+	// Far enough away to fit the whole grid in the 90 degree, 16:9 field of view
+	distance := Max(float32(columns)/2, float32(rows)/2*16/9) + 0.5
+	top := float32(rows) / 2
+
+	shapes := []Shape{
+		Plane{Vec3{0, 1, 0}, -top - 0.1, Lambertian{SolidColor{Vec3{0.5, 0.5, 0.5}}}},
+		Quad{Vec3{-4, top + 2, -2}, Vec3{6, 0, 0}, Vec3{0, 0, 4}, Emissive{Vec3{3, 3, 3}}},
+		Quad{Vec3{distance + 3, -top, -2}, Vec3{0, 0, 4}, Vec3{0, 2 * top, 0}, Emissive{Vec3{1, 1, 1}}},
+	}
+	var labels []Label
+	for i, entry := range materialLibrary {
+		row, column := i/columns, i%columns
+		center := Vec3{float32(column) - float32(columns-1)/2, float32(rows-1)/2 - float32(row), distance}
+		shapes = append(shapes, Sphere{center, 0.4, entry.Material})
+		labels = append(labels, Label{entry.Name, Sub(center, Vec3{0, 0.42, 0})})
+	}
+	return shapes, labels
+}
+
+// NewReflectiveFloor is a white floor at the given height that reflects the scene, for presenting objects
+func NewReflectiveFloor(height float32, reflectivity float32) Shape {
+	return Plane{Vec3{0, 1, 0}, height, ReflectiveFloor{Lambertian{SolidColor{Vec3{0.9, 0.9, 0.9}}}, reflectivity, 10}}
+}
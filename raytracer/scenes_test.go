@@ -0,0 +1,45 @@
+package raytracer
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// randomSphere generates shape i of a sphere field, seeding its own generator like sphereFieldScene
+func randomSphere(i int) Shape {
+	rng := rand.New(rand.NewSource(int64(i)))
+	center := Vec3{100 * rng.Float32(), 100 * rng.Float32(), 100 * rng.Float32()}
+	return Sphere{center, 0.2, Lambertian{SolidColor{Vec3{rng.Float32(), rng.Float32(), rng.Float32()}}}}
+}
+
+const benchmarkShapes = 100000
+
+// The BVH is built the same way after both, so only generating the shapes is benchmarked
+func BenchmarkBuildSequential(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		shapes := make([]Shape, benchmarkShapes)
+		for j := range shapes {
+			shapes[j] = randomSphere(j)
+		}
+	}
+}
+
+func BenchmarkBuildParallel(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		BuildParallel(randomSphere, benchmarkShapes)
+	}
+}
+
+func TestBuildParallelCallsGenForEveryIndex(t *testing.T) {
+	for _, n := range []int{0, 1, 7, 1000} {
+		shapes := BuildParallel(func(i int) Shape { return Sphere{Vec3{float32(i), 0, 0}, 1, nil} }, n)
+		if len(shapes) != n {
+			t.Fatalf("BuildParallel made %d shapes, want %d", len(shapes), n)
+		}
+		for i, shape := range shapes {
+			if shape.(Sphere).Position.X != float32(i) {
+				t.Errorf("shape %d was generated for index %v", i, shape.(Sphere).Position.X)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package raytracer
+
+// SDF is a signed distance function: negative inside the surface, positive outside
+type SDF func(Vec3) float32
+
+// sdfEpsilon and sdfMaxDistance are in meters and get multiplied by the WorldScale
+const sdfEpsilon = 1e-4
+const sdfMaxSteps = 256
+const sdfMaxDistance = 1e3
+
+// SDFShape is an implicit surface that is intersected by sphere tracing its distance function
+type SDFShape struct {
+	Distance SDF
+	Material Material
+}
+
+// Intersect marches along the ray until the distance to the surface is below sdfEpsilon
+func (shape SDFShape) Intersect(ray Ray, scale float32) (Hit, bool) {
+	// Start a little along the ray, like the other shapes, so that rays leaving the surface don't hit it again
+	t := hitEpsilon(scale)
+	for i := 0; i < sdfMaxSteps && t < sdfMaxDistance*scale; i++ {
+		distance := Abs(shape.Distance(ray.At(t)))
+		if distance < sdfEpsilon*scale {
+			return NewHit(t, ray, shape.normal(ray.At(t), scale), shape.Material), true
+		}
+		t += distance
+	}
+	return Hit{}, false
+}
+
+// BoundingBox of an SDF is infinite, because the extent of the distance function isn't known
+func (shape SDFShape) BoundingBox() (min Vec3, max Vec3) {
+	return unboundedMin, unboundedMax
+}
+
+// normal estimates the gradient of the distance function with central differences
+func (shape SDFShape) normal(p Vec3, scale float32) Vec3 {
+	h := 1e-3 * scale
+	gradient := Vec3{
+		shape.Distance(Vec3{p.X + h, p.Y, p.Z}) - shape.Distance(Vec3{p.X - h, p.Y, p.Z}),
+		shape.Distance(Vec3{p.X, p.Y + h, p.Z}) - shape.Distance(Vec3{p.X, p.Y - h, p.Z}),
+		shape.Distance(Vec3{p.X, p.Y, p.Z + h}) - shape.Distance(Vec3{p.X, p.Y, p.Z - h}),
+	}
+	return Normalize(gradient)
+}
+
+// SphereSDF is the distance function of a sphere
+func SphereSDF(center Vec3, radius float32) SDF {
+	return func(p Vec3) float32 {
+		return Sub(p, center).Length() - radius
+	}
+}
+
+// SmoothUnion blends two distance functions together, k controls the size of the blended region
+func SmoothUnion(a SDF, b SDF, k float32) SDF {
+	return func(p Vec3) float32 {
+		da := a(p)
+		db := b(p)
+		h := 0.5 + 0.5*(db-da)/k
+		if h < 0 {
+			h = 0
+		} else if h > 1 {
+			h = 1
+		}
+		return db + (da-db)*h - k*h*(1-h)
+	}
+}
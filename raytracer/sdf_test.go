@@ -0,0 +1,71 @@
+package raytracer
+
+import "testing"
+
+func TestSDFSphereMatchesSphere(t *testing.T) {
+	sphere := Sphere{Vec3{0, 0, 3}, 1, nil}
+	marched := SDFShape{SphereSDF(sphere.Position, sphere.Radius), nil}
+	// Marching stops within sdfEpsilon of the surface, and the normal is estimated a millimeter around it
+	const tolerance = 1e-3
+	tests := []struct {
+		name string
+		ray  Ray
+	}{
+		{"head-on", Ray{Vec3{0, 0, 0}, Vec3{0, 0, 1}, 0}},
+		{"off-center", Ray{Vec3{0, 0.6, 0}, Vec3{0, 0, 1}, 0}},
+		{"near tangent", Ray{Vec3{0, 0.99, 0}, Vec3{0, 0, 1}, 0}},
+		{"at an angle", Ray{Vec3{-2, 1, 0}, Normalize(Vec3{2, -1, 3}), 0}},
+		// The distance is negative inside, and marching by its absolute value reaches the exit
+		{"from inside", Ray{Vec3{0, 0.2, 3}, Normalize(Vec3{1, 0, 1}), 0}},
+	}
+	for _, test := range tests {
+		want, ok := sphere.Intersect(test.ray, 1)
+		if !ok {
+			t.Fatalf("%s: the ray missed the sphere", test.name)
+		}
+		got, ok := marched.Intersect(test.ray, 1)
+		if !ok {
+			t.Errorf("%s: the ray missed the SDF sphere", test.name)
+			continue
+		}
+		if !closeTo(got.T, want.T, tolerance) || !vecCloseTo(got.Normal, want.Normal, tolerance) {
+			t.Errorf("%s: the SDF sphere is hit at t = %v with normal %v, the sphere at %v with %v", test.name, got.T, got.Normal, want.T, want.Normal)
+		}
+	}
+
+	if _, ok := marched.Intersect(Ray{Vec3{0, 1.01, 0}, Vec3{0, 0, 1}, 0}, 1); ok {
+		t.Error("the ray just passing the SDF sphere hit it")
+	}
+	if _, ok := marched.Intersect(Ray{Vec3{0, 0, 0}, Vec3{0, 0, -1}, 0}, 1); ok {
+		t.Error("the ray away from the SDF sphere hit it")
+	}
+}
+
+func TestSDFMarchingGivesUp(t *testing.T) {
+	// A ray along a wall a little further away than sdfEpsilon never gets closer, nor further away,
+	// so the march ends after sdfMaxSteps
+	calls := 0
+	wall := func(p Vec3) float32 {
+		calls++
+		return p.Y + 2*sdfEpsilon
+	}
+	if _, ok := (SDFShape{wall, nil}).Intersect(Ray{Vec3{0, 0, 0}, Vec3{0, 0, 1}, 0}, 1); ok {
+		t.Error("the ray along the wall hit it")
+	}
+	if calls != sdfMaxSteps {
+		t.Errorf("the distance was evaluated %d times, want sdfMaxSteps (%d)", calls, sdfMaxSteps)
+	}
+}
+
+func TestSmoothUnion(t *testing.T) {
+	a, b := SphereSDF(Vec3{-1, 0, 0}, 1), SphereSDF(Vec3{1, 0, 0}, 1)
+	union := SmoothUnion(a, b, 0.5)
+	// Far from where the spheres meet it is the nearest sphere
+	if p := (Vec3{-3, 0, 0}); union(p) != a(p) {
+		t.Errorf("the smooth union at %v is %v, want the distance to the left sphere %v", p, union(p), a(p))
+	}
+	// Where they meet it bulges out beyond both
+	if p := (Vec3{0, 0.5, 0}); union(p) >= Min(a(p), b(p)) {
+		t.Errorf("the smooth union at %v is %v, want less than the distances %v and %v", p, union(p), a(p), b(p))
+	}
+}
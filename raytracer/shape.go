@@ -0,0 +1,688 @@
+package raytracer
+
+import (
+	"image"
+	"log"
+	"math"
+	"math/rand"
+)
+
+// Material of an object
+type Material interface {
+	// TODO: put result in struct?
+	Scatter(Ray, Hit, *rand.Rand) (didScatter bool, attenuation Vec3, scattered Ray)
+}
+
+// Emitter is a material that gives off light
+type Emitter interface {
+	Emitted() Vec3
+}
+
+// emitted returns the light given off by a material, which is black unless it is an Emitter
+func emitted(mat Material) Vec3 {
+	switch mat := mat.(type) {
+	case Emitter:
+		return mat.Emitted()
+	case DepthFade:
+		return emitted(mat.Material)
+	case ReflectiveFloor:
+		return emitted(mat.Base)
+	}
+	return Vec3{0, 0, 0}
+}
+
+// Emissive material, which glows with Color and doesn't reflect any light
+type Emissive struct {
+	Color Vec3
+}
+
+// Scatter never scatters a ray on an emissive material
+func (mat Emissive) Scatter(ray Ray, hit Hit, rng *rand.Rand) (didScatter bool, attenuation Vec3, scattered Ray) {
+	return false, Vec3{0, 0, 0}, Ray{}
+}
+
+// Emitted light of the material
+func (mat Emissive) Emitted() Vec3 {
+	return mat.Color
+}
+
+// Texture gives the color of a surface at every point, by its texture coordinates (u, v) or by its position p
+type Texture interface {
+	Value(u float32, v float32, p Vec3) Vec3
+}
+
+// SolidColor is a texture with the same color everywhere
+type SolidColor struct {
+	Color Vec3
+}
+
+// Value of the texture at p
+func (texture SolidColor) Value(u float32, v float32, p Vec3) Vec3 {
+	return texture.Color
+}
+
+// Checkerboard alternates between two textures in a 3D checker pattern, with squares of pi / Scale
+type Checkerboard struct {
+	Odd   Texture
+	Even  Texture
+	Scale float32
+}
+
+// Value of the texture at p
+func (texture Checkerboard) Value(u float32, v float32, p Vec3) Vec3 {
+	sines := math.Sin(float64(texture.Scale*p.X)) * math.Sin(float64(texture.Scale*p.Y)) * math.Sin(float64(texture.Scale*p.Z))
+	if sines < 0 {
+		return texture.Odd.Value(u, v, p)
+	}
+	return texture.Even.Value(u, v, p)
+}
+
+// ImageTexture maps an image onto a surface by its texture coordinates, with (0, 0) at the bottom left
+// of the image and (1, 1) at the top right
+type ImageTexture struct {
+	Image image.Image
+	// Gamma the image is encoded with, usually 2 like the output
+	Gamma float32
+}
+
+// Value of the texture at (u, v), converted back to linear colors
+func (texture ImageTexture) Value(u float32, v float32, p Vec3) Vec3 {
+	return imageColor(texture.Image, float64(u), float64(1-v), texture.Gamma)
+}
+
+// Lambertian material
+type Lambertian struct {
+	Albedo Texture
+}
+
+// Scatter a ray on a lambertian material
+func (mat Lambertian) Scatter(ray Ray, hit Hit, rng *rand.Rand) (didScatter bool, attenuation Vec3, scattered Ray) {
+	direction := scatterDirection(Add(RandomPointInUnitSphere(rng), hit.Normal), hit.Normal)
+	bouncingRay := Ray{hit.Position, direction, ray.Time}
+	return true, mat.Albedo.Value(hit.U, hit.V, hit.Position), bouncingRay
+}
+
+// scatterDirection normalizes a perturbed direction. The random perturbation can cancel out the
+// direction it was added to, in which case the unit vector fallback is used instead.
+func scatterDirection(perturbed Vec3, fallback Vec3) Vec3 {
+	direction, ok := SafeDivScalar(perturbed.Length(), perturbed)
+	if !ok {
+		return fallback
+	}
+	return direction
+}
+
+// UniformLambertian is a diffuse material like Lambertian, but it samples directions uniformly over
+// the hemisphere instead of proportionally to the cosine, which makes it noisier
+type UniformLambertian struct {
+	Albedo Vec3
+}
+
+// Scatter a ray on a uniformly sampled lambertian material
+func (mat UniformLambertian) Scatter(ray Ray, hit Hit, rng *rand.Rand) (didScatter bool, attenuation Vec3, scattered Ray) {
+	direction := RandomDirectionInHemisphere(hit.Normal, rng)
+	// The BRDF is albedo / pi and the pdf of the direction is 1 / (2 pi), so the cosine-weighted
+	// contribution of the sample is 2 * albedo * cosine
+	cosine := Dot(direction, hit.Normal)
+	return true, MulScalar(2*cosine, mat.Albedo), Ray{hit.Position, direction, ray.Time}
+}
+
+// Metal material
+type Metal struct {
+	Albedo Vec3
+	// Fuzz perturbs the reflections, from 0 for a mirror to 1. Larger values count as 1.
+	Fuzz float32
+}
+
+// Scatter a ray on a metal material
+func (mat Metal) Scatter(ray Ray, hit Hit, rng *rand.Rand) (didScatter bool, attenuation Vec3, scattered Ray) {
+	fuzz := Min(Max(mat.Fuzz, 0), 1)
+	direction := Reflect(ray.Direction, hit.Normal)
+	direction = scatterDirection(Add(direction, MulScalar(fuzz, RandomPointInUnitSphere(rng))), direction)
+	bouncingRay := Ray{hit.Position, direction, ray.Time}
+	return Dot(direction, hit.Normal) > 0, mat.Albedo, bouncingRay
+}
+
+// Conductor is a metal whose reflectance follows the Fresnel equations for its complex index of
+// refraction N + iK, given per color channel. This gives real metals their tint and their whiter
+// reflections at grazing angles.
+type Conductor struct {
+	N    Vec3
+	K    Vec3
+	Fuzz float32
+}
+
+// Gold as a conductor, with N and K sampled at red, green and blue wavelengths
+var Gold = Conductor{Vec3{0.143, 0.374, 1.442}, Vec3{3.983, 2.385, 1.603}, 0}
+
+// Copper as a conductor, with N and K sampled at red, green and blue wavelengths
+var Copper = Conductor{Vec3{0.200, 0.924, 1.102}, Vec3{3.912, 2.452, 2.142}, 0}
+
+// Aluminum as a conductor, with N and K sampled at red, green and blue wavelengths
+var Aluminum = Conductor{Vec3{1.657, 0.880, 0.521}, Vec3{9.224, 6.270, 4.837}, 0}
+
+// fresnelConductor computes the unpolarized reflectance of a conductor with index of refraction
+// n + ik for light arriving at an angle with the given cosine
+func fresnelConductor(cosine float32, n float32, k float32) float32 {
+	cos2 := float64(cosine * cosine)
+	sin2 := 1 - cos2
+	n2 := float64(n * n)
+	k2 := float64(k * k)
+	t0 := n2 - k2 - sin2
+	a2b2 := math.Sqrt(t0*t0 + 4*n2*k2)
+	a := math.Sqrt(0.5 * (a2b2 + t0))
+	t1 := a2b2 + cos2
+	t2 := 2 * a * float64(cosine)
+	rs := (t1 - t2) / (t1 + t2)
+	t3 := cos2*a2b2 + sin2*sin2
+	t4 := t2 * sin2
+	rp := rs * (t3 - t4) / (t3 + t4)
+	return float32((rs + rp) / 2)
+}
+
+// Scatter a ray on a conductor
+func (mat Conductor) Scatter(ray Ray, hit Hit, rng *rand.Rand) (didScatter bool, attenuation Vec3, scattered Ray) {
+	fuzz := Min(Max(mat.Fuzz, 0), 1)
+	direction := Reflect(ray.Direction, hit.Normal)
+	direction = scatterDirection(Add(direction, MulScalar(fuzz, RandomPointInUnitSphere(rng))), direction)
+	cosine := Min(Abs(Dot(ray.Direction, hit.Normal)), 1)
+	attenuation = Vec3{
+		fresnelConductor(cosine, mat.N.X, mat.K.X),
+		fresnelConductor(cosine, mat.N.Y, mat.K.Y),
+		fresnelConductor(cosine, mat.N.Z, mat.K.Z),
+	}
+	return Dot(direction, hit.Normal) > 0, attenuation, Ray{hit.Position, direction, ray.Time}
+}
+
+// Dielectric materials both reflect and refrect
+type Dielectric struct {
+	ReflectionIndex float32
+}
+
+func refract(incoming Vec3, normal Vec3, niOverNt float32) (didRefract bool, refraction Vec3) {
+	dt := Dot(incoming, normal)
+	discriminant := 1 - niOverNt*niOverNt*(1-dt*dt)
+	if discriminant > 0 {
+		refraction = Normalize(Sub(MulScalar(niOverNt, Sub(incoming, MulScalar(dt, normal))), MulScalar(Sqrt(discriminant), normal)))
+		return true, refraction
+	}
+	return false, Vec3{}
+}
+
+func schlick(cosine float32, reflectionIndex float32) float32 {
+	r0 := (1 - reflectionIndex) / (1 + reflectionIndex)
+	r0 *= r0
+	base := 1 - cosine
+	return r0 + (1-r0)*base*base*base*base*base
+}
+
+func assertUnitLength(v Vec3) {
+	if math.Abs(1-float64(v.Length())) > 1e-3 {
+		log.Fatal("Vector is not unit length: ", v.Length())
+	}
+}
+
+// Scatter a ray on a dielectric
+func (mat Dielectric) Scatter(ray Ray, hit Hit, rng *rand.Rand) (didScatter bool, attenuation Vec3, scattered Ray) {
+	var outwardNormal Vec3
+	var niOverNt float32
+	var cosine float32
+	if Dot(ray.Direction, hit.Normal) > 0 {
+		outwardNormal = MulScalar(-1, hit.Normal)
+		niOverNt = mat.ReflectionIndex
+		// Schlick's approximation needs the angle on the outside of the medium, which is the angle of
+		// the refracted ray. Without refraction (total internal reflection) it is never used.
+		cosine = Dot(ray.Direction, hit.Normal)
+		cosine = Sqrt(Max(0, 1-mat.ReflectionIndex*mat.ReflectionIndex*(1-cosine*cosine)))
+	} else {
+		outwardNormal = hit.Normal
+		niOverNt = 1.0 / mat.ReflectionIndex
+		cosine = -Dot(ray.Direction, hit.Normal)
+	}
+
+	// Always reflect on total internal reflection, otherwise reflect with the probability given by Schlick
+	didRefract, refracted := refract(ray.Direction, outwardNormal, niOverNt)
+	if !didRefract || rng.Float32() < schlick(cosine, mat.ReflectionIndex) {
+		return true, Vec3{1, 1, 1}, Ray{hit.Position, Reflect(ray.Direction, hit.Normal), ray.Time}
+	}
+	return true, Vec3{1, 1, 1}, Ray{hit.Position, refracted, ray.Time}
+}
+
+// DepthFade wraps a material and makes it fade to transparent with distance. It is fully opaque up to
+// FadeStart along the ray, and fully transparent from FadeEnd. For camera rays that is the distance
+// from the camera, for bounced rays the distance from the previous hit. With FadeEnd at or before
+// FadeStart it turns transparent at once at FadeEnd.
+type DepthFade struct {
+	Material  Material
+	FadeStart float32
+	FadeEnd   float32
+}
+
+// Scatter a ray on the wrapped material, or let it continue unchanged with a probability given by the fade
+func (mat DepthFade) Scatter(ray Ray, hit Hit, rng *rand.Rand) (didScatter bool, attenuation Vec3, scattered Ray) {
+	var opacity float32 = 1
+	if hit.T >= mat.FadeEnd {
+		opacity = 0
+	} else if hit.T > mat.FadeStart {
+		opacity = 1 - (hit.T-mat.FadeStart)/(mat.FadeEnd-mat.FadeStart)
+	}
+	if rng.Float32() >= opacity {
+		return true, Vec3{1, 1, 1}, Ray{hit.Position, ray.Direction, ray.Time}
+	}
+	return mat.Material.Scatter(ray, hit, rng)
+}
+
+// passedThrough reports whether a DepthFade let ray continue unchanged into scattered instead of
+// scattering it on its material
+func passedThrough(mat Material, ray Ray, scattered Ray) bool {
+	_, fades := mat.(DepthFade)
+	return fades && scattered.Direction == ray.Direction
+}
+
+// isSpecular reports whether a material scattered ray into scattered at hit like a mirror or glass
+// rather than diffusely. Most materials always do one or the other, but a ReflectiveFloor has a mirror coat
+// on a base material, so it depends on whether the ray was mirrored.
+func isSpecular(mat Material, ray Ray, hit Hit, scattered Ray) bool {
+	switch mat := mat.(type) {
+	case Metal, Conductor, Dielectric:
+		return true
+	case DepthFade:
+		return isSpecular(mat.Material, ray, hit, scattered)
+	case ReflectiveFloor:
+		return scattered.Direction == Reflect(ray.Direction, hit.Normal) || isSpecular(mat.Base, ray, hit, scattered)
+	}
+	return false
+}
+
+// ReflectiveFloor is a diffuse material with a mirror-like coat whose reflections fade toward the
+// horizon: the chance of a mirror reflection is Reflectivity, and it halves every FadeDistance along
+// the ray.
+type ReflectiveFloor struct {
+	Base         Material
+	Reflectivity float32
+	FadeDistance float32
+}
+
+// Scatter a ray either as a mirror reflection or on the base material
+func (mat ReflectiveFloor) Scatter(ray Ray, hit Hit, rng *rand.Rand) (didScatter bool, attenuation Vec3, scattered Ray) {
+	strength := mat.Reflectivity * float32(math.Exp2(-float64(hit.T/mat.FadeDistance)))
+	if rng.Float32() < strength {
+		return true, Vec3{1, 1, 1}, Ray{hit.Position, Reflect(ray.Direction, hit.Normal), ray.Time}
+	}
+	return mat.Base.Scatter(ray, hit, rng)
+}
+
+// Shape in the world
+type Shape interface {
+	// Intersect returns the closest hit of the ray, ignoring those closer than hitEpsilon(scale),
+	// where scale is the WorldScale of the render
+	Intersect(ray Ray, scale float32) (Hit, bool)
+	// BoundingBox returns the corners of an axis-aligned box around the shape
+	BoundingBox() (min Vec3, max Vec3)
+}
+
+// hitEpsilon is the distance along a ray below which hits are rejected, so that rays leaving a
+// surface don't hit that same surface again. It is a millimeter in a scene with the given number of
+// units per meter (RenderConfig.WorldScale).
+func hitEpsilon(scale float32) float32 {
+	return 1e-3 * scale
+}
+
+// parallelEpsilon is the cosine below which a ray is considered parallel to a plane.
+// It is an angle rather than a distance, so it doesn't depend on the WorldScale.
+const parallelEpsilon = 1e-6
+
+// Sphere in 3D space
+type Sphere struct {
+	Position Vec3
+	Radius   float32
+	Material Material
+}
+
+// Intersect check whether the ray intersects the sphere
+func (sphere Sphere) Intersect(ray Ray, scale float32) (Hit, bool) {
+	a := Dot(ray.Direction, ray.Direction)
+	relPos := Sub(ray.Origin, sphere.Position)
+	halfB := Dot(ray.Direction, relPos)
+	c := Dot(relPos, relPos) - sphere.Radius*sphere.Radius
+
+	discriminant := halfB*halfB - a*c
+	if discriminant < 0 {
+		return Hit{}, false
+	}
+
+	// -halfB and the square root have the same sign in q, so computing the roots from it doesn't
+	// subtract nearly equal numbers, which loses the precision of the near root of large spheres
+	q := -halfB - Sqrt(discriminant)
+	if halfB < 0 {
+		q = -halfB + Sqrt(discriminant)
+	}
+	if q == 0 {
+		return Hit{}, false
+	}
+	t0, t1 := q/a, c/q
+	if t0 > t1 {
+		t0, t1 = t1, t0
+	}
+	t := t0
+	// Rays that start on the surface, like refracted rays, have their near root at (almost) zero
+	if t <= hitEpsilon(scale) {
+		t = t1
+	}
+	if t <= hitEpsilon(scale) {
+		return Hit{}, false
+	}
+
+	normal := Normalize(DivScalar(sphere.Radius, Sub(ray.At(t), sphere.Position)))
+	hit := NewHit(t, ray, normal, sphere.Material)
+	hit.U, hit.V = sphereUV(normal)
+	return hit, true
+}
+
+// sphereUV gives the texture coordinates of the point p on a unit sphere around the origin: u is the
+// angle around the Y axis, starting at -X, and v goes from 0 at the south pole to 1 at the north pole
+func sphereUV(p Vec3) (u float32, v float32) {
+	phi := math.Atan2(float64(p.Z), float64(p.X))
+	theta := math.Asin(math.Max(-1, math.Min(1, float64(p.Y))))
+	return float32((phi + math.Pi) / (2 * math.Pi)), float32((theta + math.Pi/2) / math.Pi)
+}
+
+// BoundingBox of the sphere
+func (sphere Sphere) BoundingBox() (min Vec3, max Vec3) {
+	extent := Vec3{sphere.Radius, sphere.Radius, sphere.Radius}
+	return Sub(sphere.Position, extent), Add(sphere.Position, extent)
+}
+
+// MovingSphere moves from Center0 at time 0 to Center1 at time 1
+type MovingSphere struct {
+	Center0  Vec3
+	Center1  Vec3
+	Radius   float32
+	Material Material
+}
+
+// center of the sphere at the given time
+func (sphere MovingSphere) center(time float32) Vec3 {
+	return Lerp(sphere.Center0, sphere.Center1, time)
+}
+
+// Intersect checks whether the ray intersects the sphere where it is at the time of the ray
+func (sphere MovingSphere) Intersect(ray Ray, scale float32) (Hit, bool) {
+	return Sphere{sphere.center(ray.Time), sphere.Radius, sphere.Material}.Intersect(ray, scale)
+}
+
+// BoundingBox of the sphere over its whole path
+func (sphere MovingSphere) BoundingBox() (min Vec3, max Vec3) {
+	min0, max0 := Sphere{sphere.Center0, sphere.Radius, sphere.Material}.BoundingBox()
+	min1, max1 := Sphere{sphere.Center1, sphere.Radius, sphere.Material}.BoundingBox()
+	return minVec3(min0, min1), maxVec3(max0, max1)
+}
+
+// Plane in 3D space
+type Plane struct {
+	Normal   Vec3
+	Along    float32
+	Material Material
+}
+
+// Intersect checks if a ray intersects with the plane
+func (plane Plane) Intersect(ray Ray, scale float32) (Hit, bool) {
+	denom := Dot(plane.Normal, ray.Direction)
+	if math.Abs(float64(denom)) < parallelEpsilon {
+		return Hit{}, false
+	}
+	planePoint := MulScalar(plane.Along, plane.Normal)
+	t := (Dot(planePoint, plane.Normal) - Dot(plane.Normal, ray.Origin)) / denom
+	if t < hitEpsilon(scale) {
+		return Hit{}, false
+	}
+	// The plane has two sides, so the normal faces whichever side the ray comes from
+	normal := plane.Normal
+	if denom > 0 {
+		normal = MulScalar(-1, normal)
+	}
+	return NewHit(t, ray, normal, plane.Material), true
+}
+
+// BoundingBox of a plane is infinite
+func (plane Plane) BoundingBox() (min Vec3, max Vec3) {
+	return unboundedMin, unboundedMax
+}
+
+// Quad is a parallelogram with one corner at Corner and edges U and V from there, like a rectangular
+// wall or area light
+type Quad struct {
+	Corner   Vec3
+	U        Vec3
+	V        Vec3
+	Material Material
+}
+
+// Intersect intersects the plane of the quad and checks whether the hit lies within its edges
+func (quad Quad) Intersect(ray Ray, scale float32) (Hit, bool) {
+	n := Cross(quad.U, quad.V)
+	normal := Normalize(n)
+	denom := Dot(normal, ray.Direction)
+	if math.Abs(float64(denom)) < parallelEpsilon {
+		return Hit{}, false
+	}
+	t := Dot(normal, Sub(quad.Corner, ray.Origin)) / denom
+	if t < hitEpsilon(scale) {
+		return Hit{}, false
+	}
+	// Express the hit position as Corner + alpha U + beta V
+	d := Sub(ray.At(t), quad.Corner)
+	w := DivScalar(Dot(n, n), n)
+	alpha := Dot(w, Cross(d, quad.V))
+	beta := Dot(w, Cross(quad.U, d))
+	if alpha < 0 || alpha > 1 || beta < 0 || beta > 1 {
+		return Hit{}, false
+	}
+	// Like the plane, the quad has two sides and the normal faces the side the ray comes from
+	if denom > 0 {
+		normal = MulScalar(-1, normal)
+	}
+	hit := NewHit(t, ray, normal, quad.Material)
+	hit.U, hit.V = alpha, beta
+	return hit, true
+}
+
+// BoundingBox of the quad, which is flat along an axis if the quad is aligned with it
+func (quad Quad) BoundingBox() (min Vec3, max Vec3) {
+	opposite := Add(quad.Corner, Add(quad.U, quad.V))
+	min = minVec3(minVec3(quad.Corner, opposite), minVec3(Add(quad.Corner, quad.U), Add(quad.Corner, quad.V)))
+	max = maxVec3(maxVec3(quad.Corner, opposite), maxVec3(Add(quad.Corner, quad.U), Add(quad.Corner, quad.V)))
+	return min, max
+}
+
+// Box is an axis-aligned box from Min to Max
+type Box struct {
+	Min      Vec3
+	Max      Vec3
+	Material Material
+}
+
+// Intersect checks if a ray intersects with the box, using the slab method
+func (box Box) Intersect(ray Ray, scale float32) (Hit, bool) {
+	tNear, tFar := float32(-math.MaxFloat32), float32(math.MaxFloat32)
+	var nearNormal, farNormal Vec3
+	for axis := 0; axis < 3; axis++ {
+		origin := ray.Origin.Component(axis)
+		direction := ray.Direction.Component(axis)
+		if Abs(direction) < parallelEpsilon {
+			// Parallel to the slab, so either always or never between its faces
+			if origin < box.Min.Component(axis) || origin > box.Max.Component(axis) {
+				return Hit{}, false
+			}
+			continue
+		}
+		t0 := (box.Min.Component(axis) - origin) / direction
+		t1 := (box.Max.Component(axis) - origin) / direction
+		// The ray enters the slab through the face it is travelling towards and leaves through the other one
+		sign := float32(1)
+		if t0 > t1 {
+			t0, t1 = t1, t0
+			sign = -1
+		}
+		if t0 > tNear {
+			tNear, nearNormal = t0, axisVector(axis, -sign)
+		}
+		if t1 < tFar {
+			tFar, farNormal = t1, axisVector(axis, sign)
+		}
+		if tFar < tNear {
+			return Hit{}, false
+		}
+	}
+	// Rays that start inside the box hit the face they leave through
+	if tNear > hitEpsilon(scale) {
+		return NewHit(tNear, ray, nearNormal, box.Material), true
+	}
+	if tFar > hitEpsilon(scale) {
+		return NewHit(tFar, ray, farNormal, box.Material), true
+	}
+	return Hit{}, false
+}
+
+// axisVector returns the unit vector along the X, Y or Z axis for axis 0, 1 or 2, multiplied by sign
+func axisVector(axis int, sign float32) Vec3 {
+	switch axis {
+	case 0:
+		return Vec3{sign, 0, 0}
+	case 1:
+		return Vec3{0, sign, 0}
+	}
+	return Vec3{0, 0, sign}
+}
+
+// BoundingBox of the box is the box itself
+func (box Box) BoundingBox() (min Vec3, max Vec3) {
+	return box.Min, box.Max
+}
+
+// Cylinder with its base centered on Center, going up Height along the unit vector Axis. Without caps it is an
+// open tube.
+type Cylinder struct {
+	Center   Vec3
+	Axis     Vec3
+	Radius   float32
+	Height   float32
+	Capped   bool
+	Material Material
+}
+
+// Intersect checks if a ray intersects with the side or the caps of the cylinder
+func (cylinder Cylinder) Intersect(ray Ray, scale float32) (Hit, bool) {
+	// Split the ray into the parts along the axis and perpendicular to it. The perpendicular part
+	// hits the infinite cylinder where it is Radius away from the axis.
+	relPos := Sub(ray.Origin, cylinder.Center)
+	originAlong := Dot(relPos, cylinder.Axis)
+	directionAlong := Dot(ray.Direction, cylinder.Axis)
+	originPerp := Sub(relPos, MulScalar(originAlong, cylinder.Axis))
+	directionPerp := Sub(ray.Direction, MulScalar(directionAlong, cylinder.Axis))
+
+	var closest Hit
+	found := false
+	a := Dot(directionPerp, directionPerp)
+	b := 2 * Dot(directionPerp, originPerp)
+	c := Dot(originPerp, originPerp) - cylinder.Radius*cylinder.Radius
+	discriminant := b*b - 4*a*c
+	if a > parallelEpsilon && discriminant >= 0 {
+		for _, t := range []float32{(-b - Sqrt(discriminant)) / (2 * a), (-b + Sqrt(discriminant)) / (2 * a)} {
+			height := originAlong + t*directionAlong
+			if t > hitEpsilon(scale) && 0 <= height && height <= cylinder.Height {
+				normal := DivScalar(cylinder.Radius, Add(originPerp, MulScalar(t, directionPerp)))
+				closest, found = NewHit(t, ray, normal, cylinder.Material), true
+				break
+			}
+		}
+	}
+
+	if !cylinder.Capped || Abs(directionAlong) < parallelEpsilon {
+		return closest, found
+	}
+	for _, height := range []float32{0, cylinder.Height} {
+		t := (height - originAlong) / directionAlong
+		if t <= hitEpsilon(scale) || (found && t >= closest.T) {
+			continue
+		}
+		if Add(originPerp, MulScalar(t, directionPerp)).SquaredLength() > cylinder.Radius*cylinder.Radius {
+			continue
+		}
+		normal := cylinder.Axis
+		if height == 0 {
+			normal = MulScalar(-1, normal)
+		}
+		closest, found = NewHit(t, ray, normal, cylinder.Material), true
+	}
+	return closest, found
+}
+
+// BoundingBox of the cylinder, by putting a box of the radius around both ends
+func (cylinder Cylinder) BoundingBox() (min Vec3, max Vec3) {
+	top := Add(cylinder.Center, MulScalar(cylinder.Height, cylinder.Axis))
+	extent := Vec3{cylinder.Radius, cylinder.Radius, cylinder.Radius}
+	return Sub(minVec3(cylinder.Center, top), extent), Add(maxVec3(cylinder.Center, top), extent)
+}
+
+// Triangle in 3D space. Vertices are counter-clockwise
+type Triangle struct {
+	V1       Vec3
+	V2       Vec3
+	V3       Vec3
+	Material Material
+}
+
+// Different parameterisation of the plane
+type planeWithPoint struct {
+	Normal   Vec3
+	Point    Vec3
+	Material Material
+}
+
+// Intersect checks if a ray intersects with the plane
+func (plane planeWithPoint) Intersect(ray Ray, scale float32) (Hit, bool) {
+	denom := Dot(plane.Normal, ray.Direction)
+	if math.Abs(float64(denom)) < parallelEpsilon {
+		return Hit{}, false
+	}
+	t := (Dot(plane.Point, plane.Normal) - Dot(plane.Normal, ray.Origin)) / denom
+	if t < hitEpsilon(scale) {
+		return Hit{}, false
+	}
+	return NewHit(t, ray, plane.Normal, plane.Material), true
+}
+
+// BoundingBox of a plane is infinite
+func (plane planeWithPoint) BoundingBox() (min Vec3, max Vec3) {
+	return unboundedMin, unboundedMax
+}
+
+// Intersect checks if a ray intersects with the triangle
+func (triangle Triangle) Intersect(ray Ray, scale float32) (Hit, bool) {
+	// First we find out where on the plane of the triangle the ray intersects
+	relV2 := Sub(triangle.V2, triangle.V1)
+	relV3 := Sub(triangle.V3, triangle.V1)
+	// We don't normalize directly, because the calucations below need the unnormalized normal
+	normal := Cross(relV2, relV3)
+	plane := planeWithPoint{Normalize(normal), triangle.V1, triangle.Material}
+	hit, ok := plane.Intersect(ray, scale)
+	if !ok {
+		return Hit{}, false
+	}
+
+	// Use the barycentric representation to find out if the point is inside the triangle
+	normalSquaredLength := normal.SquaredLength()
+	gamma := Dot(Cross(relV2, Sub(hit.Position, triangle.V1)), normal) / normalSquaredLength
+	beta := Dot(Cross(Sub(hit.Position, triangle.V1), relV3), normal) / normalSquaredLength
+	alpha := 1 - gamma - beta
+	if 0 <= alpha && alpha <= 1 && 0 <= beta && beta <= 1 && 0 <= gamma && gamma <= 1 {
+		return hit, true
+	}
+	return Hit{}, false
+}
+
+// BoundingBox of the triangle
+func (triangle Triangle) BoundingBox() (min Vec3, max Vec3) {
+	return minVec3(minVec3(triangle.V1, triangle.V2), triangle.V3), maxVec3(maxVec3(triangle.V1, triangle.V2), triangle.V3)
+}
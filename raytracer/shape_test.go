@@ -0,0 +1,416 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// closeTo reports whether a and b differ by at most tolerance
+func closeTo(a float32, b float32, tolerance float32) bool {
+	return Abs(a-b) <= tolerance
+}
+
+// vecCloseTo reports whether every component of a and b differs by at most tolerance
+func vecCloseTo(a Vec3, b Vec3, tolerance float32) bool {
+	return closeTo(a.X, b.X, tolerance) && closeTo(a.Y, b.Y, tolerance) && closeTo(a.Z, b.Z, tolerance)
+}
+
+// refractedRay scatters ray on the dielectric at hit until it refracts instead of reflecting, which is
+// when it continues to the other side of the surface
+func refractedRay(t *testing.T, mat Dielectric, ray Ray, hit Hit) Ray {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	side := Dot(ray.Direction, hit.Normal)
+	for i := 0; i < 100; i++ {
+		_, _, scattered := mat.Scatter(ray, hit, rng)
+		if Dot(scattered.Direction, hit.Normal)*side > 0 {
+			return scattered
+		}
+	}
+	t.Fatal("the ray never refracted")
+	return Ray{}
+}
+
+// sine of the angle between a unit direction and the line through a unit normal
+func sine(direction Vec3, normal Vec3) float32 {
+	return Cross(direction, normal).Length()
+}
+
+func TestDielectricRefractionFollowsSnellsLaw(t *testing.T) {
+	glass := Dielectric{1.5}
+	sphere := Sphere{Vec3{0, 0, 0}, 1, glass}
+	ray := Ray{Vec3{0, 0.6, -5}, Vec3{0, 0, 1}, 0}
+
+	entry, ok := sphere.Intersect(ray, 1)
+	if !ok {
+		t.Fatal("the ray missed the sphere")
+	}
+	inside := refractedRay(t, glass, ray, entry)
+	if got, want := sine(inside.Direction, entry.Normal), sine(ray.Direction, entry.Normal)/1.5; !closeTo(got, want, 1e-4) {
+		t.Errorf("entering: sine of the refracted angle is %v, want %v", got, want)
+	}
+
+	exit, ok := sphere.Intersect(inside, 1)
+	if !ok {
+		t.Fatal("the refracted ray didn't reach the other side of the sphere")
+	}
+	if !closeTo(exit.Position.Length(), 1, 1e-4) {
+		t.Fatalf("exit point %v is not on the sphere", exit.Position)
+	}
+	outside := refractedRay(t, glass, inside, exit)
+	if got, want := sine(outside.Direction, exit.Normal), 1.5*sine(inside.Direction, exit.Normal); !closeTo(got, want, 1e-4) {
+		t.Errorf("exiting: sine of the refracted angle is %v, want %v", got, want)
+	}
+	// The path through a sphere is symmetric, so the ray leaves at the angle it entered at
+	if got, want := sine(outside.Direction, exit.Normal), float32(0.6); !closeTo(got, want, 1e-4) {
+		t.Errorf("the ray leaves at an angle with sine %v, want %v", got, want)
+	}
+	// and is bent towards the axis of the sphere by twice the difference of the angles
+	deviation := 2 * (math.Asin(0.6) - math.Asin(0.6/1.5))
+	if got, want := outside.Direction, (Vec3{0, -float32(math.Sin(deviation)), float32(math.Cos(deviation))}); !vecCloseTo(got, want, 1e-4) {
+		t.Errorf("the ray leaves in direction %v, want %v", got, want)
+	}
+}
+
+// meanResponse averages the light a material reflects from a hit with an upward normal, under a sky
+// that is equally bright in every direction above it
+func meanResponse(mat Material, samples int) Vec3 {
+	rng := rand.New(rand.NewSource(1))
+	ray := Ray{Vec3{0, 1, 0}, Vec3{0, -1, 0}, 0}
+	hit := NewHit(1, ray, Vec3{0, 1, 0}, mat)
+	sum := Vec3{0, 0, 0}
+	for i := 0; i < samples; i++ {
+		didScatter, attenuation, scattered := mat.Scatter(ray, hit, rng)
+		if didScatter && scattered.Direction.Y > 0 {
+			sum = Add(sum, attenuation)
+		}
+	}
+	return DivScalar(float32(samples), sum)
+}
+
+func TestUniformLambertianConvergesLikeLambertian(t *testing.T) {
+	albedo := Vec3{0.8, 0.5, 0.2}
+	cosineWeighted := meanResponse(Lambertian{SolidColor{albedo}}, 100000)
+	uniform := meanResponse(UniformLambertian{albedo}, 100000)
+	if !vecCloseTo(cosineWeighted, albedo, 0.01) {
+		t.Errorf("Lambertian reflects %v, want %v", cosineWeighted, albedo)
+	}
+	if !vecCloseTo(uniform, albedo, 0.01) {
+		t.Errorf("UniformLambertian reflects %v, want %v", uniform, albedo)
+	}
+}
+
+func TestReflectiveFloorIsSpecularOnlyWhenMirrored(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ray := Ray{Vec3{0, 1, -1}, Normalize(Vec3{0, -1, 1}), 0}
+	base := Lambertian{SolidColor{Vec3{0.9, 0.9, 0.9}}}
+	for _, test := range []struct {
+		reflectivity float32
+		want         bool
+	}{{1, true}, {0, false}} {
+		mat := ReflectiveFloor{base, test.reflectivity, 1e9}
+		hit := NewHit(1, ray, Vec3{0, 1, 0}, mat)
+		_, _, scattered := mat.Scatter(ray, hit, rng)
+		if got := isSpecular(mat, ray, hit, scattered); got != test.want {
+			t.Errorf("with reflectivity %v, isSpecular is %v, want %v", test.reflectivity, got, test.want)
+		}
+	}
+}
+
+func TestDepthFadeWithoutAFadeIsACutoff(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ray := Ray{Vec3{0, 0, 0}, Vec3{0, 0, 1}, 0}
+	mat := DepthFade{Lambertian{SolidColor{Vec3{0.5, 0.5, 0.5}}}, 2, 2}
+	for i := 0; i < 100; i++ {
+		near := NewHit(1, ray, Vec3{0, 0, -1}, mat)
+		if _, _, scattered := mat.Scatter(ray, near, rng); passedThrough(mat, ray, scattered) {
+			t.Fatal("a ray passed through the surface before the cutoff")
+		}
+		far := NewHit(3, ray, Vec3{0, 0, -1}, mat)
+		if _, _, scattered := mat.Scatter(ray, far, rng); !passedThrough(mat, ray, scattered) {
+			t.Fatalf("a ray scattered into %v after the cutoff, want it to pass through", scattered.Direction)
+		}
+	}
+}
+
+func TestPassingThroughADepthFadeIsNotABounce(t *testing.T) {
+	cfg := testConfig()
+	cfg.Bounces, cfg.MaxDiffuse = 0, 0
+	light := Sphere{Vec3{0, 0, 5}, 1, Emissive{Vec3{1, 1, 1}}}
+	faded := Sphere{Vec3{0, 0, 2}, 0.5, DepthFade{Lambertian{SolidColor{Vec3{0.5, 0.5, 0.5}}}, 0, 0}}
+	scene := NewScene([]Shape{light, faded}, Camera{}, SolidBackground{})
+	rng := rand.New(rand.NewSource(1))
+	if got := scene.Trace(Ray{Vec3{0, 0, 0}, Vec3{0, 0, 1}, 0}, rng, &cfg); got != (Vec3{1, 1, 1}) {
+		t.Errorf("the ray through the faded sphere gathered %v, want the light behind it (1, 1, 1)", got)
+	}
+}
+
+func TestBoxHitsEveryFace(t *testing.T) {
+	box := Box{Vec3{-1, -2, -3}, Vec3{1, 2, 3}, nil}
+	tests := []struct {
+		origin    Vec3
+		direction Vec3
+		position  Vec3
+		normal    Vec3
+	}{
+		{Vec3{-5, 0.5, 0.5}, Vec3{1, 0, 0}, Vec3{-1, 0.5, 0.5}, Vec3{-1, 0, 0}},
+		{Vec3{5, 0.5, 0.5}, Vec3{-1, 0, 0}, Vec3{1, 0.5, 0.5}, Vec3{1, 0, 0}},
+		{Vec3{0.5, -5, 0.5}, Vec3{0, 1, 0}, Vec3{0.5, -2, 0.5}, Vec3{0, -1, 0}},
+		{Vec3{0.5, 5, 0.5}, Vec3{0, -1, 0}, Vec3{0.5, 2, 0.5}, Vec3{0, 1, 0}},
+		{Vec3{0.5, 0.5, -5}, Vec3{0, 0, 1}, Vec3{0.5, 0.5, -3}, Vec3{0, 0, -1}},
+		{Vec3{0.5, 0.5, 5}, Vec3{0, 0, -1}, Vec3{0.5, 0.5, 3}, Vec3{0, 0, 1}},
+		// From inside, the ray hits the face it leaves through
+		{Vec3{0, 0, 0}, Vec3{1, 0, 0}, Vec3{1, 0, 0}, Vec3{1, 0, 0}},
+	}
+	for _, test := range tests {
+		hit, ok := box.Intersect(Ray{test.origin, test.direction, 0}, 1)
+		if !ok {
+			t.Errorf("the ray from %v in direction %v missed the box", test.origin, test.direction)
+			continue
+		}
+		if !vecCloseTo(hit.Position, test.position, 1e-5) || hit.Normal != test.normal {
+			t.Errorf("the ray from %v in direction %v hit %v with normal %v, want %v with normal %v",
+				test.origin, test.direction, hit.Position, hit.Normal, test.position, test.normal)
+		}
+	}
+	if _, ok := box.Intersect(Ray{Vec3{-5, 2.5, 0}, Vec3{1, 0, 0}, 0}, 1); ok {
+		t.Error("a ray passing above the box hit it")
+	}
+}
+
+func TestCylinder(t *testing.T) {
+	open := Cylinder{Vec3{0, 0, 0}, Vec3{0, 1, 0}, 1, 2, false, nil}
+	capped := open
+	capped.Capped = true
+	tests := []struct {
+		name     string
+		cylinder Cylinder
+		ray      Ray
+		hit      bool
+		position Vec3
+		normal   Vec3
+	}{
+		{"side", open, Ray{Vec3{-5, 1, 0}, Vec3{1, 0, 0}, 0}, true, Vec3{-1, 1, 0}, Vec3{-1, 0, 0}},
+		{"side below the top", capped, Ray{Vec3{0, 1.5, 5}, Vec3{0, 0, -1}, 0}, true, Vec3{0, 1.5, 1}, Vec3{0, 0, 1}},
+		{"top cap", capped, Ray{Vec3{0.5, 5, 0}, Vec3{0, -1, 0}, 0}, true, Vec3{0.5, 2, 0}, Vec3{0, 1, 0}},
+		{"bottom cap", capped, Ray{Vec3{0.5, -5, 0}, Vec3{0, 1, 0}, 0}, true, Vec3{0.5, 0, 0}, Vec3{0, -1, 0}},
+		{"through the open ends", open, Ray{Vec3{0.5, 5, 0}, Vec3{0, -1, 0}, 0}, false, Vec3{}, Vec3{}},
+		{"above the top", capped, Ray{Vec3{-5, 2.5, 0}, Vec3{1, 0, 0}, 0}, false, Vec3{}, Vec3{}},
+	}
+	for _, test := range tests {
+		hit, ok := test.cylinder.Intersect(test.ray, 1)
+		if ok != test.hit {
+			t.Errorf("%s: hit is %v, want %v", test.name, ok, test.hit)
+			continue
+		}
+		if ok && (!vecCloseTo(hit.Position, test.position, 1e-5) || !vecCloseTo(hit.Normal, test.normal, 1e-5)) {
+			t.Errorf("%s: hit %v with normal %v, want %v with normal %v", test.name, hit.Position, hit.Normal, test.position, test.normal)
+		}
+	}
+	// Looking into the open tube from above, the ray hits the inside of the side
+	hit, ok := open.Intersect(Ray{Vec3{0, 3, 0}, Normalize(Vec3{0.5, -1, 0}), 0}, 1)
+	if !ok || !closeTo(hit.Position.X, 1, 1e-5) || hit.Position.Y < 0 || hit.Position.Y > 2 {
+		t.Errorf("a ray into the open end hit %v at %v, want the inside of the side", ok, hit.Position)
+	}
+}
+
+func TestMovingSphereIsHitWhereItIsAtTheTimeOfTheRay(t *testing.T) {
+	sphere := MovingSphere{Vec3{0, 0, 2}, Vec3{2, 0, 2}, 0.5, nil}
+	hit, ok := sphere.Intersect(Ray{Vec3{1, 0, -5}, Vec3{0, 0, 1}, 0.5}, 1)
+	if !ok || !vecCloseTo(hit.Position, Vec3{1, 0, 1.5}, 1e-5) {
+		t.Errorf("at time 0.5 the ray hit %v at %v, want the sphere centered on (1, 0, 2) at (1, 0, 1.5)", ok, hit.Position)
+	}
+	if _, ok := sphere.Intersect(Ray{Vec3{1, 0, -5}, Vec3{0, 0, 1}, 0}, 1); ok {
+		t.Error("at time 0 the ray hit the sphere, which is still centered on (0, 0, 2)")
+	}
+}
+
+func TestMetalFuzzIsClampedToOne(t *testing.T) {
+	ray := Ray{Vec3{0, 1, -1}, Normalize(Vec3{0, -1, 1}), 0}
+	hit := NewHit(1, ray, Vec3{0, 1, 0}, nil)
+	clamped, rough := rand.New(rand.NewSource(1)), rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		wantOk, wantAttenuation, want := Metal{Vec3{0.8, 0.6, 0.2}, 1}.Scatter(ray, hit, clamped)
+		ok, attenuation, got := Metal{Vec3{0.8, 0.6, 0.2}, 2}.Scatter(ray, hit, rough)
+		if ok != wantOk || attenuation != wantAttenuation || got != want {
+			t.Fatalf("with fuzz 2 the ray scattered %v to %v, with fuzz 1 %v to %v", ok, got, wantOk, want)
+		}
+	}
+}
+
+func TestConductorFuzzIsClampedToOne(t *testing.T) {
+	ray := Ray{Vec3{0, 1, -1}, Normalize(Vec3{0, -1, 1}), 0}
+	hit := NewHit(1, ray, Vec3{0, 1, 0}, nil)
+	clamped, rough := rand.New(rand.NewSource(1)), rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		wantOk, wantAttenuation, want := Conductor{Gold.N, Gold.K, 1}.Scatter(ray, hit, clamped)
+		ok, attenuation, got := Conductor{Gold.N, Gold.K, 2}.Scatter(ray, hit, rough)
+		if ok != wantOk || attenuation != wantAttenuation || got != want {
+			t.Fatalf("with fuzz 2 the ray scattered %v to %v, with fuzz 1 %v to %v", ok, got, wantOk, want)
+		}
+	}
+}
+
+func TestConductorReflectanceAtNormalIncidence(t *testing.T) {
+	// Head-on, the Fresnel equations reduce to ((n-1)^2 + k^2) / ((n+1)^2 + k^2)
+	reflectance := func(n float32, k float32) float32 {
+		return ((n-1)*(n-1) + k*k) / ((n+1)*(n+1) + k*k)
+	}
+	ray := Ray{Vec3{0, 1, 0}, Vec3{0, -1, 0}, 0}
+	hit := NewHit(1, ray, Vec3{0, 1, 0}, nil)
+	rng := rand.New(rand.NewSource(1))
+	for name, mat := range map[string]Conductor{"gold": Gold, "copper": Copper, "aluminum": Aluminum} {
+		want := Vec3{reflectance(mat.N.X, mat.K.X), reflectance(mat.N.Y, mat.K.Y), reflectance(mat.N.Z, mat.K.Z)}
+		if _, got, _ := mat.Scatter(ray, hit, rng); !vecCloseTo(got, want, 1e-5) {
+			t.Errorf("%s reflects %v head-on, want %v", name, got, want)
+		}
+	}
+}
+
+func TestPlaneNormalFacesTheRay(t *testing.T) {
+	plane := Plane{Vec3{0, 1, 0}, -1, nil}
+	tests := []struct {
+		origin     Vec3
+		direction  Vec3
+		wantNormal Vec3
+	}{
+		{Vec3{0, 2, 0}, Normalize(Vec3{1, -1, 0}), Vec3{0, 1, 0}},
+		{Vec3{0, -3, 0}, Normalize(Vec3{0, 1, 1}), Vec3{0, -1, 0}},
+	}
+	for _, test := range tests {
+		hit, ok := plane.Intersect(Ray{test.origin, test.direction, 0}, 1)
+		if !ok {
+			t.Errorf("the ray from %v missed the plane", test.origin)
+			continue
+		}
+		if hit.Normal != test.wantNormal || !closeTo(hit.Position.Y, -1, 1e-5) {
+			t.Errorf("the ray from %v hit at %v with normal %v, want y = -1 and %v", test.origin, hit.Position, hit.Normal, test.wantNormal)
+		}
+	}
+	if _, ok := plane.Intersect(Ray{Vec3{0, 2, 0}, Vec3{1, 0, 0}, 0}, 1); ok {
+		t.Error("a ray parallel to the plane hit it")
+	}
+	if _, ok := plane.Intersect(Ray{Vec3{0, 2, 0}, Vec3{0, 1, 0}, 0}, 1); ok {
+		t.Error("a ray going away from the plane hit it")
+	}
+}
+
+func TestSphereUV(t *testing.T) {
+	sphere := Sphere{Vec3{0, 0, 0}, 1, nil}
+	tests := []struct {
+		origin    Vec3
+		direction Vec3
+		wantV     float32
+	}{
+		{Vec3{0, 5, 0}, Vec3{0, -1, 0}, 1},
+		{Vec3{0, -5, 0}, Vec3{0, 1, 0}, 0},
+		{Vec3{0, 0, -5}, Vec3{0, 0, 1}, 0.5},
+		{Vec3{5, 0, 0}, Vec3{-1, 0, 0}, 0.5},
+	}
+	for _, test := range tests {
+		hit, ok := sphere.Intersect(Ray{test.origin, test.direction, 0}, 1)
+		if !ok || !closeTo(hit.V, test.wantV, 1e-3) {
+			t.Errorf("the ray from %v hit %v with v = %v, want %v", test.origin, ok, hit.V, test.wantV)
+		}
+		if hit.U < 0 || hit.U > 1 {
+			t.Errorf("the ray from %v hit with u = %v, outside [0, 1]", test.origin, hit.U)
+		}
+	}
+	// u goes around the equator, from -X through -Z to +X half way
+	if u, _ := sphereUV(Vec3{1, 0, 0}); !closeTo(u, 0.5, 1e-5) {
+		t.Errorf("u at +X is %v, want 0.5", u)
+	}
+	if u, _ := sphereUV(Vec3{0, 0, -1}); !closeTo(u, 0.25, 1e-5) {
+		t.Errorf("u at -Z is %v, want 0.25", u)
+	}
+}
+
+func TestLargeSphereIntersectionIsPrecise(t *testing.T) {
+	// The ground of the scenes, made ten times larger, seen at a low angle from just above it
+	sphere := Sphere{Vec3{0, -1000.5, 1}, 1000, nil}
+	origin, direction := Vec3{0, 0, 0}, Normalize(Vec3{0, -0.05, 1})
+	hit, ok := sphere.Intersect(Ray{origin, direction, 0}, 1)
+	if !ok {
+		t.Fatal("the ray missed the sphere")
+	}
+	// Solve the quadratic in float64
+	relPos := [3]float64{float64(origin.X - sphere.Position.X), float64(origin.Y - sphere.Position.Y), float64(origin.Z - sphere.Position.Z)}
+	dir := [3]float64{float64(direction.X), float64(direction.Y), float64(direction.Z)}
+	halfB := relPos[0]*dir[0] + relPos[1]*dir[1] + relPos[2]*dir[2]
+	c := relPos[0]*relPos[0] + relPos[1]*relPos[1] + relPos[2]*relPos[2] - 1000*1000
+	a := dir[0]*dir[0] + dir[1]*dir[1] + dir[2]*dir[2]
+	want := (-halfB - math.Sqrt(halfB*halfB-a*c)) / a
+	if math.Abs(float64(hit.T)-want) > 1e-4*want {
+		t.Errorf("the ray hit at t = %v, want %v", hit.T, want)
+	}
+}
+
+func TestQuad(t *testing.T) {
+	// 2 wide and 1 high in the plane z = 3
+	quad := Quad{Vec3{-1, 0, 3}, Vec3{2, 0, 0}, Vec3{0, 1, 0}, nil}
+	tests := []struct {
+		name      string
+		origin    Vec3
+		direction Vec3
+		wantOk    bool
+		want      Vec3
+	}{
+		{"inside", Vec3{0.5, 0.5, 0}, Vec3{0, 0, 1}, true, Vec3{0.5, 0.5, 3}},
+		{"from behind", Vec3{0.5, 0.5, 5}, Vec3{0, 0, -1}, true, Vec3{0.5, 0.5, 3}},
+		{"just outside an edge", Vec3{1.01, 0.5, 0}, Vec3{0, 0, 1}, false, Vec3{}},
+		{"just above the top", Vec3{0, 1.01, 0}, Vec3{0, 0, 1}, false, Vec3{}},
+		{"grazing", Vec3{-2, 0.5, 3}, Vec3{1, 0, 0}, false, Vec3{}},
+	}
+	for _, test := range tests {
+		hit, ok := quad.Intersect(Ray{test.origin, test.direction, 0}, 1)
+		if ok != test.wantOk {
+			t.Errorf("%s: the ray hit %v, want %v", test.name, ok, test.wantOk)
+			continue
+		}
+		if ok && (!vecCloseTo(hit.Position, test.want, 1e-5) || Dot(hit.Normal, test.direction) >= 0) {
+			t.Errorf("%s: the ray hit at %v with normal %v, want %v facing the ray", test.name, hit.Position, hit.Normal, test.want)
+		}
+	}
+	if min, max := quad.BoundingBox(); min != (Vec3{-1, 0, 3}) || max != (Vec3{1, 1, 3}) {
+		t.Errorf("the bounding box is from %v to %v, want (-1, 0, 3) to (1, 1, 3)", min, max)
+	}
+	// The BVH pads the flat box, so rays still reach the quad through it
+	bvh := NewBVH([]Shape{quad, Sphere{Vec3{5, 5, 5}, 1, nil}})
+	if _, ok := bvh.Intersect(Ray{Vec3{0.5, 0.5, 0}, Vec3{0, 0, 1}, 0}, 1); !ok {
+		t.Error("the ray missed the quad in a BVH")
+	}
+}
+
+// reflectedShare is the share of the rays that the dielectric reflects instead of refracting
+func reflectedShare(mat Dielectric, ray Ray, hit Hit, rays int) float32 {
+	rng := rand.New(rand.NewSource(1))
+	side := Dot(ray.Direction, hit.Normal)
+	reflected := 0
+	for i := 0; i < rays; i++ {
+		_, _, scattered := mat.Scatter(ray, hit, rng)
+		if Dot(scattered.Direction, hit.Normal)*side < 0 {
+			reflected++
+		}
+	}
+	return float32(reflected) / float32(rays)
+}
+
+func TestDielectricReflectsMostlyAtGrazingIncidence(t *testing.T) {
+	glass := Dielectric{1.5}
+	normal := Vec3{0, 1, 0}
+	// 89 degrees from the normal, from outside the glass
+	grazing := Ray{Vec3{0, 1, 0}, Normalize(Vec3{0, -0.0175, 1}), 0}
+	if share := reflectedShare(glass, grazing, NewHit(1, grazing, normal, glass), 10000); share <= 0.5 {
+		t.Errorf("%v of the rays at grazing incidence are reflected, want most of them", share)
+	}
+	headOn := Ray{Vec3{0, 1, 0}, Vec3{0, -1, 0}, 0}
+	if share := reflectedShare(glass, headOn, NewHit(1, headOn, normal, glass), 10000); share > 0.1 {
+		t.Errorf("%v of the rays at normal incidence are reflected, want about 4%%", share)
+	}
+	// From inside the glass, beyond the critical angle of 42 degrees
+	inside := Ray{Vec3{0, -1, 0}, Normalize(Vec3{0, 1, 1.5}), 0}
+	if share := reflectedShare(glass, inside, NewHit(1, inside, normal, glass), 1000); share != 1 {
+		t.Errorf("%v of the rays beyond the critical angle are reflected, want all of them", share)
+	}
+}
@@ -0,0 +1,80 @@
+package raytracer
+
+import "math"
+
+// Translate moves a shape by Offset, so that one shape can be placed at several positions
+type Translate struct {
+	Shape  Shape
+	Offset Vec3
+}
+
+// Intersect moves the ray into the space of the shape instead of moving the shape
+func (translate Translate) Intersect(ray Ray, scale float32) (Hit, bool) {
+	hit, ok := translate.Shape.Intersect(Ray{Sub(ray.Origin, translate.Offset), ray.Direction, ray.Time}, scale)
+	if !ok {
+		return Hit{}, false
+	}
+	hit.Position = Add(hit.Position, translate.Offset)
+	return hit, true
+}
+
+// BoundingBox of the moved shape
+func (translate Translate) BoundingBox() (min Vec3, max Vec3) {
+	min, max = translate.Shape.BoundingBox()
+	if isUnbounded(min, max) {
+		return min, max
+	}
+	return Add(min, translate.Offset), Add(max, translate.Offset)
+}
+
+// RotateY rotates a shape around the Y axis
+type RotateY struct {
+	Shape    Shape
+	sinTheta float32
+	cosTheta float32
+}
+
+// NewRotateY rotates shape counterclockwise (seen from above) by the given angle in degrees
+func NewRotateY(shape Shape, degrees float32) RotateY {
+	theta := float64(Deg2Rad(degrees))
+	return RotateY{shape, float32(math.Sin(theta)), float32(math.Cos(theta))}
+}
+
+// rotate a vector from the space of the shape into the world
+func (rotate RotateY) rotate(v Vec3) Vec3 {
+	return Vec3{rotate.cosTheta*v.X + rotate.sinTheta*v.Z, v.Y, -rotate.sinTheta*v.X + rotate.cosTheta*v.Z}
+}
+
+// unrotate a vector from the world into the space of the shape
+func (rotate RotateY) unrotate(v Vec3) Vec3 {
+	return Vec3{rotate.cosTheta*v.X - rotate.sinTheta*v.Z, v.Y, rotate.sinTheta*v.X + rotate.cosTheta*v.Z}
+}
+
+// Intersect rotates the ray the other way into the space of the shape, and the hit back out of it.
+// Rotations keep lengths and angles, so the normal is rotated like the position.
+func (rotate RotateY) Intersect(ray Ray, scale float32) (Hit, bool) {
+	hit, ok := rotate.Shape.Intersect(Ray{rotate.unrotate(ray.Origin), rotate.unrotate(ray.Direction), ray.Time}, scale)
+	if !ok {
+		return Hit{}, false
+	}
+	hit.Position = rotate.rotate(hit.Position)
+	hit.Normal = rotate.rotate(hit.Normal)
+	return hit, true
+}
+
+// BoundingBox around the rotated corners of the bounding box of the shape
+func (rotate RotateY) BoundingBox() (min Vec3, max Vec3) {
+	innerMin, innerMax := rotate.Shape.BoundingBox()
+	if isUnbounded(innerMin, innerMax) {
+		return unboundedMin, unboundedMax
+	}
+	min, max = unboundedMax, unboundedMin
+	for _, x := range []float32{innerMin.X, innerMax.X} {
+		for _, z := range []float32{innerMin.Z, innerMax.Z} {
+			corner := rotate.rotate(Vec3{x, innerMin.Y, z})
+			min, max = minVec3(min, corner), maxVec3(max, corner)
+		}
+	}
+	min.Y, max.Y = innerMin.Y, innerMax.Y
+	return min, max
+}
@@ -0,0 +1,30 @@
+package raytracer
+
+import "testing"
+
+func TestRotateYMovesTheHit(t *testing.T) {
+	// Counterclockwise seen from above, +X turns to -Z
+	rotated := NewRotateY(Sphere{Vec3{1, 0, 0}, 0.5, nil}, 90)
+	hit, ok := rotated.Intersect(Ray{Vec3{0, 0, -5}, Vec3{0, 0, 1}, 0}, 1)
+	if !ok {
+		t.Fatal("the ray missed the sphere rotated in front of it")
+	}
+	if !vecCloseTo(hit.Position, Vec3{0, 0, -1.5}, 1e-5) || !vecCloseTo(hit.Normal, Vec3{0, 0, -1}, 1e-5) {
+		t.Errorf("the ray hit at %v with normal %v, want (0, 0, -1.5) and (0, 0, -1)", hit.Position, hit.Normal)
+	}
+	if !closeTo(hit.T, 3.5, 1e-5) {
+		t.Errorf("the ray hit at t = %v, want 3.5", hit.T)
+	}
+	// Where the sphere was before rotating it
+	if _, ok := rotated.Intersect(Ray{Vec3{1, 0, -5}, Vec3{0, 0, 1}, 0}, 1); ok {
+		t.Error("the ray through the unrotated position of the sphere hit it")
+	}
+}
+
+func TestTranslateMovesTheHit(t *testing.T) {
+	moved := Translate{Sphere{Vec3{0, 0, 0}, 0.5, nil}, Vec3{2, 0, 3}}
+	hit, ok := moved.Intersect(Ray{Vec3{2, 0, -5}, Vec3{0, 0, 1}, 0}, 1)
+	if !ok || !vecCloseTo(hit.Position, Vec3{2, 0, 2.5}, 1e-5) || !vecCloseTo(hit.Normal, Vec3{0, 0, -1}, 1e-5) {
+		t.Errorf("the ray hit %v at %v with normal %v, want (2, 0, 2.5) and (0, 0, -1)", ok, hit.Position, hit.Normal)
+	}
+}
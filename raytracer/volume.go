@@ -0,0 +1,64 @@
+package raytracer
+
+import (
+	"math"
+	"math/rand"
+)
+
+// ConstantMedium fills the inside of a convex shape with fog or smoke of a uniform Density: the chance
+// of a ray scattering in it grows exponentially with the distance that it travels through it
+type ConstantMedium struct {
+	Boundary Shape
+	Density  float32
+	Phase    Material
+}
+
+// Intersect picks a random point along the part of the ray inside the boundary at which it scatters,
+// or reports no hit if it goes through without scattering
+func (medium ConstantMedium) Intersect(ray Ray, scale float32) (Hit, bool) {
+	first, ok := medium.Boundary.Intersect(ray, scale)
+	if !ok {
+		return Hit{}, false
+	}
+	// The normals of the boundary point outwards, so this is the exit if the ray starts inside
+	enter, exit := float32(0), first.T
+	if Dot(ray.Direction, first.Normal) < 0 {
+		second, ok := medium.Boundary.Intersect(Ray{first.Position, ray.Direction, ray.Time}, scale)
+		if !ok {
+			return Hit{}, false
+		}
+		enter, exit = first.T, first.T+second.T
+	}
+
+	distance := float32(-math.Log(rayRandom(ray))) / medium.Density
+	if distance > exit-enter {
+		return Hit{}, false
+	}
+	return NewHit(enter+distance, ray, Vec3{1, 0, 0}, medium.Phase), true
+}
+
+// BoundingBox of the medium is that of its boundary
+func (medium ConstantMedium) BoundingBox() (min Vec3, max Vec3) {
+	return medium.Boundary.BoundingBox()
+}
+
+// rayRandom returns a random number in (0, 1] that is a hash of the ray. Intersect has no random number
+// generator, and this keeps renders reproducible where a shared generator would not be.
+func rayRandom(ray Ray) float64 {
+	h := uint64(0)
+	for _, f := range []float32{ray.Origin.X, ray.Origin.Y, ray.Origin.Z, ray.Direction.X, ray.Direction.Y, ray.Direction.Z, ray.Time} {
+		h = mix64(h ^ uint64(math.Float32bits(f)))
+	}
+	return (float64(h>>11) + 1) / (1 << 53)
+}
+
+// Isotropic material scatters rays in a uniformly random direction, as in fog
+type Isotropic struct {
+	Albedo Texture
+}
+
+// Scatter a ray in a random direction
+func (mat Isotropic) Scatter(ray Ray, hit Hit, rng *rand.Rand) (didScatter bool, attenuation Vec3, scattered Ray) {
+	direction := scatterDirection(RandomPointInUnitSphere(rng), ray.Direction)
+	return true, mat.Albedo.Value(hit.U, hit.V, hit.Position), Ray{hit.Position, direction, ray.Time}
+}
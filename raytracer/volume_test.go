@@ -0,0 +1,45 @@
+package raytracer
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// meanScatterT is the average distance at which rays that scatter in the medium do so, and the
+// fraction of the rays that scatter
+func meanScatterT(medium ConstantMedium, rays int) (meanT float32, scattered float32) {
+	rng := rand.New(rand.NewSource(1))
+	var sum float32
+	hits := 0
+	for i := 0; i < rays; i++ {
+		direction := Normalize(Vec3{0.1 * RandomUniform(rng), 0.1 * RandomUniform(rng), 1})
+		if hit, ok := medium.Intersect(Ray{Vec3{0, 0, 0}, direction, 0}, 1); ok {
+			sum += hit.T
+			hits++
+		}
+	}
+	return sum / float32(hits), float32(hits) / float32(rays)
+}
+
+func TestDenserMediumScattersEarlier(t *testing.T) {
+	boundary := Sphere{Vec3{0, 0, 10}, 3, nil}
+	thinT, thinScattered := meanScatterT(ConstantMedium{boundary, 0.1, nil}, 10000)
+	denseT, denseScattered := meanScatterT(ConstantMedium{boundary, 1, nil}, 10000)
+	if denseT >= thinT {
+		t.Errorf("rays scatter at %v on average in the dense medium, at %v in the thin one", denseT, thinT)
+	}
+	if denseScattered <= thinScattered {
+		t.Errorf("%v of the rays scatter in the dense medium, %v in the thin one", denseScattered, thinScattered)
+	}
+	// Every hit lies inside the boundary
+	if denseT < 7 || thinT > 13 {
+		t.Errorf("the mean scatter distances %v and %v are outside the sphere from 7 to 13", denseT, thinT)
+	}
+}
+
+func TestMediumScattersRaysStartingInside(t *testing.T) {
+	medium := ConstantMedium{Sphere{Vec3{0, 0, 0}, 1000, nil}, 1, nil}
+	if _, scattered := meanScatterT(medium, 100); scattered != 1 {
+		t.Errorf("%v of the rays starting inside a dense medium scatter, want all", scattered)
+	}
+}